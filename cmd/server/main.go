@@ -1,9 +1,23 @@
 package main
 
-import "github.com/rudransh-shrivastava/rudransh-backend-task/internal/api"
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/api"
+)
 
 // Entry point of the application
+// SIGINT and SIGTERM (sent by docker stop) shut the server down gracefully
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	server := api.NewServer()
-	server.Run()
+	if err := server.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
 }
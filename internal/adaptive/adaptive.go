@@ -0,0 +1,57 @@
+package adaptive
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// Abilities and difficulties share a logit scale (the Rasch model): a student answers a question
+// as difficult as their ability half of the time, and a question one step easier about 73% of the time.
+
+// StartAbility is the estimate before the first answer, the ability of an average student
+const StartAbility = 0.0
+
+// maxStep is how far the first answer can move the estimate, later answers move it less
+const maxStep = 1.0
+
+// Difficulty places the stored difficulty of a question on the ability scale
+func Difficulty(d schema.Difficulty) float64 {
+	switch d {
+	case schema.Easy:
+		return -1
+	case schema.Hard:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Probability is the chance of a student of the given ability to answer a question of the given difficulty correctly
+func Probability(ability, difficulty float64) float64 {
+	return 1 / (1 + math.Exp(difficulty-ability))
+}
+
+// Update returns the ability estimate after an answer earning credit (0 to 1) to a question of the given difficulty
+// It moves like an Elo rating, by the surprise of the answer, in steps which shrink with the number of answers before
+func Update(ability, difficulty, credit float64, answered int) float64 {
+	step := maxStep / (1 + float64(answered)/2)
+	return ability + step*(credit-Probability(ability, difficulty))
+}
+
+// Next returns the index of the question to serve next among the questions not served yet, false if all were served
+// It is the question whose difficulty is the closest to the ability, which tells the most about it.
+// Ties are broken in a random order drawn from seed, so students of the same ability get different questions
+func Next(questions []schema.QuizQuestion, served func(position int) bool, ability float64, seed int64) (int, bool) {
+	next, best := -1, math.Inf(1)
+	for _, i := range rand.New(rand.NewSource(seed)).Perm(len(questions)) {
+		if served(questions[i].Position) {
+			continue
+		}
+		if distance := math.Abs(Difficulty(questions[i].Question.Difficulty) - ability); distance < best {
+			next, best = i, distance
+		}
+	}
+	return next, next >= 0
+}
@@ -0,0 +1,60 @@
+package adaptive
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+func TestUpdate(t *testing.T) {
+	// a right answer raises the estimate, more for a harder question
+	easy := Update(StartAbility, Difficulty(schema.Easy), 1, 0)
+	hard := Update(StartAbility, Difficulty(schema.Hard), 1, 0)
+	if !(StartAbility < easy && easy < hard) {
+		t.Errorf("expected 0 < %f < %f", easy, hard)
+	}
+	if wrong := Update(StartAbility, Difficulty(schema.Medium), 0, 0); wrong != -0.5 {
+		t.Errorf("expected a wrong answer to a medium question to lower the estimate to -0.5, got %f", wrong)
+	}
+	// later answers move the estimate less
+	if first, later := Update(0, 0, 1, 0), Update(0, 0, 1, 4); later >= first {
+		t.Errorf("expected a smaller step after 4 answers, got %f and %f", first, later)
+	}
+	if p := Probability(1, 0); math.Abs(p-0.731) > 1e-3 {
+		t.Errorf("expected a probability of 0.731, got %f", p)
+	}
+}
+
+func TestNext(t *testing.T) {
+	questions := []schema.QuizQuestion{
+		{Position: 0, Question: schema.Question{Difficulty: schema.Easy}},
+		{Position: 1, Question: schema.Question{Difficulty: schema.Medium}},
+		{Position: 2, Question: schema.Question{Difficulty: schema.Hard}},
+		{Position: 3, Question: schema.Question{Difficulty: schema.Hard}},
+	}
+	none := func(int) bool { return false }
+
+	if next, _ := Next(questions, none, 0, 1); next != 1 {
+		t.Errorf("expected the medium question for an average student, got %d", next)
+	}
+	if next, _ := Next(questions, none, 0.8, 1); next != 2 && next != 3 {
+		t.Errorf("expected a hard question for a strong student, got %d", next)
+	}
+	if next, _ := Next(questions, func(p int) bool { return p != 0 }, 3, 1); next != 0 {
+		t.Errorf("expected the only question left, got %d", next)
+	}
+	if _, found := Next(questions, func(int) bool { return true }, 0, 1); found {
+		t.Errorf("expected no question once all were served")
+	}
+
+	// ties are broken by the seed
+	picked := map[int]bool{}
+	for seed := range int64(20) {
+		next, _ := Next(questions, none, 2, seed)
+		picked[next] = true
+	}
+	if len(picked) != 2 || !picked[2] || !picked[3] {
+		t.Errorf("expected both hard questions to be picked for some seeds, got %v", picked)
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/adaptive"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// nextQuestion is the question of an adaptive attempt waiting for an answer
+type nextQuestion struct {
+	AttemptID uint            `json:"attempt_id"`
+	Number    int             `json:"number"` // 1 for the first question served in the attempt
+	Total     int             `json:"total"`  // questions served in the attempt
+	Deadline  *time.Time      `json:"deadline"`
+	Question  studentQuestion `json:"question"`
+}
+
+// adaptiveLength returns how many questions an adaptive attempt at the quiz is served
+func adaptiveLength(quiz *schema.Quiz) int {
+	if quiz.AdaptiveLength > 0 && quiz.AdaptiveLength < len(quiz.Questions) {
+		return quiz.AdaptiveLength
+	}
+	return len(quiz.Questions)
+}
+
+// quizQuestionAt returns the question of the quiz at the position
+func quizQuestionAt(quiz *schema.Quiz, position int) (schema.QuizQuestion, bool) {
+	i := slices.IndexFunc(quiz.Questions, func(qq schema.QuizQuestion) bool { return qq.Position == position })
+	if i < 0 {
+		return schema.QuizQuestion{}, false
+	}
+	return quiz.Questions[i], true
+}
+
+// attemptAbility returns the current ability estimate of an adaptive attempt
+func attemptAbility(attempt *schema.QuizAttempt) float64 {
+	if attempt.Ability == nil {
+		return adaptive.StartAbility
+	}
+	return *attempt.Ability
+}
+
+// servedIn reports if the question at a position was already served in the attempt
+func servedIn(attempt *schema.QuizAttempt) func(position int) bool {
+	return func(position int) bool {
+		return slices.ContainsFunc(attempt.Answers, func(a schema.AttemptAnswer) bool { return a.Position == position })
+	}
+}
+
+// loadAdaptiveQuiz loads an adaptive quiz and the current user, writing an error unless the user can take it
+func (s *Server) loadAdaptiveQuiz(w http.ResponseWriter, r *http.Request) (*schema.Quiz, *schema.User, bool) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	quiz, err := s.quizStore.GetQuizById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "quiz")
+		return nil, nil, false
+	}
+	if !quiz.Adaptive {
+		utils.WriteErrorResponse(w, "the quiz is not adaptive", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return nil, nil, false
+	}
+	if !s.requireEnrollment(w, r, user, quiz.CourseID) {
+		return nil, nil, false
+	}
+	return quiz, user, true
+}
+
+// Handler to get the next question of an adaptive quiz, starting an attempt if there is none in progress
+// The question waiting for an answer is returned again until it is answered
+func (s *Server) getNextQuestion(w http.ResponseWriter, r *http.Request) {
+	quiz, user, ok := s.loadAdaptiveQuiz(w, r)
+	if !ok {
+		return
+	}
+	attempt, err := s.beginAttempt(r.Context(), quiz, attemptSettings(quiz, roleFromContext(r)), user.ID, variantSeed(quiz.Seed, user.ID), time.Now())
+	if err != nil {
+		s.log(r).Errorf("Failed to start attempt of quiz %d: %v", quiz.ID, err)
+		writeAttemptError(w, err)
+		return
+	}
+
+	if attempt.Pending == nil {
+		next, found := adaptive.Next(quiz.Questions, servedIn(attempt), attemptAbility(attempt), attempt.VariantSeed)
+		if !found || len(attempt.Answers) >= adaptiveLength(quiz) {
+			utils.WriteErrorResponse(w, "every question of the attempt was answered", http.StatusConflict)
+			return
+		}
+		attempt.Pending = &quiz.Questions[next].Position
+		if err := s.quizStore.UpdateAttempt(r.Context(), attempt); err != nil {
+			utils.WriteErrorResponse(w, "failed to serve the next question", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	qq, found := quizQuestionAt(quiz, *attempt.Pending)
+	if !found {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, nextQuestion{
+		AttemptID: attempt.ID,
+		Number:    len(attempt.Answers) + 1,
+		Total:     adaptiveLength(quiz),
+		Deadline:  attempt.Deadline,
+		Question: studentQuestion{
+			ID:       qq.Question.ID,
+			Position: qq.Position,
+			Points:   qq.Points,
+			Type:     scoring.Type(&qq.Question),
+			Question: qq.Question.Question,
+			Options:  qq.Question.Options,
+			Matches:  qq.Question.Matches,
+		},
+	})
+}
+
+// Handler to answer the question of an adaptive attempt waiting for an answer
+// The ability estimate is updated with the answer, the attempt is submitted once its last question is answered
+func (s *Server) answerQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Answer    scoring.Response `json:"answer"`
+		TimeSpent *float64         `json:"time_spent"` // optional, seconds spent on the question
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TimeSpent != nil && *req.TimeSpent < 0 {
+		utils.WriteErrorResponse(w, "time_spent must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	quiz, user, ok := s.loadAdaptiveQuiz(w, r)
+	if !ok {
+		return
+	}
+	attempt, err := s.quizStore.GetOpenAttempt(r.Context(), user.ID, quiz.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "failed to answer question", http.StatusInternalServerError)
+		return
+	}
+	if attempt == nil || attempt.Pending == nil {
+		utils.WriteErrorResponse(w, "no question is waiting for an answer, get the next question first", http.StatusConflict)
+		return
+	}
+
+	settings := attemptSettings(quiz, roleFromContext(r))
+	now := time.Now()
+	late := pastDeadline(attempt, now)
+	if late && lateRejected(settings) {
+		if err := s.closeExpiredAttempt(r.Context(), quiz, attempt); err != nil {
+			s.log(r).Errorf("Failed to close attempt %d: %v", attempt.ID, err)
+		}
+		writeAttemptError(w, errPastDeadline)
+		return
+	}
+	qq, found := quizQuestionAt(quiz, *attempt.Pending)
+	if !found {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rules := quiz.Settings.Scoring
+	result := scoring.GradeQuiz([]schema.QuizQuestion{qq}, []scoring.Response{req.Answer}, rules).Results[0]
+	answer := schema.AttemptAnswer{Position: qq.Position, Points: result.Points, Correct: result.Correct(), TimeSpent: req.TimeSpent}
+	if scoring.IsList(&qq.Question) {
+		answer.Answers = req.Answer
+	} else {
+		answer.Answer = req.Answer.Single()
+	}
+	ability := adaptive.Update(attemptAbility(attempt), adaptive.Difficulty(qq.Question.Difficulty), result.Credit, len(attempt.Answers))
+	attempt.Ability = &ability
+	attempt.Answers = append(attempt.Answers, answer)
+	attempt.Pending = nil
+	scoreAdaptiveAttempt(attempt, quiz)
+
+	if len(attempt.Answers) < adaptiveLength(quiz) {
+		if err := s.quizStore.UpdateAttempt(r.Context(), attempt); err != nil {
+			utils.WriteErrorResponse(w, "failed to answer question", http.StatusInternalServerError)
+			return
+		}
+		utils.WriteJSONResponse(w, attempt)
+		return
+	}
+
+	attempt.SubmittedAt = &now
+	attempt.Late = late
+	if late && settings.LatePolicy == schema.LatePenalize {
+		attempt.Score *= 1 - settings.LatePenalty
+		attempt.Passed = scoring.Passed(rules, attempt.Score, attempt.MaxScore)
+	}
+	if err := s.submitAttempt(r.Context(), quiz, attempt); err != nil {
+		utils.WriteErrorResponse(w, "failed to submit quiz", http.StatusInternalServerError)
+		return
+	}
+	s.log(r).Debugf("User %d scored %.0f/%.0f on adaptive quiz %d with an ability of %.2f", user.ID, attempt.Score, attempt.MaxScore, quiz.ID, ability)
+	utils.WriteJSONResponse(w, attempt)
+}
+
+// closeAdaptiveAttempt fills the questions left in an adaptive attempt which ran out of time with blank answers
+// They are picked as if they had been served, so they count in the max score like unanswered questions of other quizzes
+func closeAdaptiveAttempt(attempt *schema.QuizAttempt, quiz *schema.Quiz) {
+	ability, served := attemptAbility(attempt), servedIn(attempt)
+	for len(attempt.Answers) < adaptiveLength(quiz) {
+		position := attempt.Pending
+		if position == nil {
+			next, found := adaptive.Next(quiz.Questions, served, ability, attempt.VariantSeed)
+			if !found {
+				break
+			}
+			position = &quiz.Questions[next].Position
+		}
+		attempt.Answers = append(attempt.Answers, schema.AttemptAnswer{Position: *position})
+		attempt.Pending = nil
+	}
+	scoreAdaptiveAttempt(attempt, quiz)
+}
+
+// scoreAdaptiveAttempt sums up the answers of an adaptive attempt
+// The max score only counts the questions served, the ones left in the quiz could not be answered
+func scoreAdaptiveAttempt(attempt *schema.QuizAttempt, quiz *schema.Quiz) {
+	attempt.Score, attempt.MaxScore = 0, 0
+	for _, answer := range attempt.Answers {
+		qq, _ := quizQuestionAt(quiz, answer.Position)
+		attempt.Score += answer.Points
+		attempt.MaxScore += qq.Points
+	}
+	attempt.Score = max(attempt.Score, 0)
+	attempt.Passed = scoring.Passed(quiz.Settings.Scoring, attempt.Score, attempt.MaxScore)
+}
+
+// validateAdaptive checks the adaptive options of a quiz of the given number of questions
+func validateAdaptive(adaptive bool, length, number int, variants bool) error {
+	switch {
+	case !adaptive && length != 0:
+		return errors.New("adaptive_length is only used by adaptive quizzes")
+	case length < 0:
+		return errors.New("adaptive_length must not be negative")
+	case length > number:
+		return fmt.Errorf("adaptive_length must not be more than the %d questions of the quiz", number)
+	case adaptive && variants:
+		return errors.New("per_student_variants can not be combined with adaptive, adaptive quizzes already serve every student their own questions")
+	}
+	return nil
+}
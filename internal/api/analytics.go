@@ -0,0 +1,205 @@
+package api
+
+import (
+	"cmp"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// Questions are only flagged once they have enough responses for the statistics to mean something
+const minFlagResponses = 5
+
+// itemStats are the statistics of a question of the bank over every submitted attempt of the course
+type itemStats struct {
+	QuestionID     uint                `json:"question_id"`
+	Question       string              `json:"question"`
+	Type           schema.QuestionType `json:"type"`
+	Responses      int                 `json:"responses"`
+	Blank          int                 `json:"blank"`             // responses left unanswered
+	PercentCorrect *float64            `json:"percent_correct"`   // difficulty index, null without responses
+	Discrimination *float64            `json:"discrimination"`    // from -1 to 1, null with less than 2 responses
+	Options        []optionStats       `json:"options,omitempty"` // how often every option was picked, choice questions only
+	AvgTimeSpent   *float64            `json:"avg_time_spent"`    // seconds, null if no client reported it
+	Flags          []string            `json:"flags"`
+}
+
+type optionStats struct {
+	Option  string `json:"option"`
+	Correct bool   `json:"correct"`
+	Picked  int    `json:"picked"`
+}
+
+// analyzeItems computes the statistics of every question of the bank from its responses
+func analyzeItems(questions []schema.Question, responses []store.ItemResponse) []itemStats {
+	byQuestion := map[uint][]store.ItemResponse{}
+	for _, r := range responses {
+		byQuestion[r.QuestionID] = append(byQuestion[r.QuestionID], r)
+	}
+	stats := make([]itemStats, len(questions))
+	for i := range questions {
+		stats[i] = analyzeItem(&questions[i], byQuestion[questions[i].ID])
+	}
+	return stats
+}
+
+func analyzeItem(q *schema.Question, responses []store.ItemResponse) itemStats {
+	stats := itemStats{QuestionID: q.ID, Question: q.Question, Type: scoring.Type(q), Responses: len(responses), Flags: []string{}}
+	stats.Options = choiceOptions(q)
+
+	correct, timed, timeSpent := 0, 0, 0.0
+	for _, r := range responses {
+		picks := r.Answers
+		if r.Answer != "" {
+			picks = []string{r.Answer}
+		}
+		if len(picks) == 0 {
+			stats.Blank++
+		}
+		if r.Correct {
+			correct++
+		}
+		if r.TimeSpent != nil {
+			timed++
+			timeSpent += *r.TimeSpent
+		}
+		for j := range stats.Options {
+			if slices.ContainsFunc(picks, func(p string) bool { return sameText(p, stats.Options[j].Option) }) {
+				stats.Options[j].Picked++
+			}
+		}
+	}
+	if len(responses) > 0 {
+		p := round2(float64(correct) / float64(len(responses)) * 100)
+		stats.PercentCorrect = &p
+	}
+	if timed > 0 {
+		avg := round2(timeSpent / float64(timed))
+		stats.AvgTimeSpent = &avg
+	}
+	stats.Discrimination = discrimination(responses)
+
+	if len(responses) >= minFlagResponses {
+		stats.Flags = itemFlags(stats)
+	}
+	return stats
+}
+
+// choiceOptions returns the options of the question to count picks for, nil if it is not a choice question
+func choiceOptions(q *schema.Question) []optionStats {
+	var correct func(string) bool
+	switch scoring.Type(q) {
+	case schema.SingleChoice, schema.TrueFalse:
+		correct = func(option string) bool { return sameText(option, q.Answer) }
+	case schema.MultiSelect:
+		correct = func(option string) bool {
+			return slices.ContainsFunc(q.Answers, func(a string) bool { return sameText(option, a) })
+		}
+	default:
+		return nil
+	}
+	options := make([]optionStats, len(q.Options))
+	for i, option := range q.Options {
+		options[i] = optionStats{Option: option, Correct: correct(option)}
+	}
+	return options
+}
+
+// discrimination compares how often the question is answered correctly in the best and the worst attempts,
+// the top and bottom 27% by score. Good questions are answered correctly more often in the best attempts
+func discrimination(responses []store.ItemResponse) *float64 {
+	if len(responses) < 2 {
+		return nil
+	}
+	ranked := slices.Clone(responses)
+	fraction := func(r store.ItemResponse) float64 {
+		if r.MaxScore == 0 {
+			return 0
+		}
+		return r.Score / r.MaxScore
+	}
+	slices.SortStableFunc(ranked, func(a, b store.ItemResponse) int {
+		return cmp.Compare(fraction(b), fraction(a))
+	})
+
+	group := max(1, int(math.Round(0.27*float64(len(ranked)))))
+	correctIn := func(responses []store.ItemResponse) float64 {
+		correct := 0
+		for _, r := range responses {
+			if r.Correct {
+				correct++
+			}
+		}
+		return float64(correct) / float64(len(responses))
+	}
+	d := round2(correctIn(ranked[:group]) - correctIn(ranked[len(ranked)-group:]))
+	return &d
+}
+
+// itemFlags points out what looks wrong with a question
+func itemFlags(stats itemStats) []string {
+	flags := []string{}
+	if p := stats.PercentCorrect; p != nil && *p > 90 {
+		flags = append(flags, "too_easy")
+	} else if p != nil && *p < 30 {
+		flags = append(flags, "too_hard")
+	}
+	if d := stats.Discrimination; d != nil && *d < 0 {
+		flags = append(flags, "negative_discrimination")
+	} else if d != nil && *d < 0.2 {
+		flags = append(flags, "low_discrimination")
+	}
+	if slices.ContainsFunc(stats.Options, func(o optionStats) bool { return !o.Correct && o.Picked == 0 }) {
+		flags = append(flags, "unused_distractor")
+	}
+	return flags
+}
+
+func sameText(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// Handler to get the item analysis of the question bank of a course
+// flagged=true only returns the questions which look poorly written
+func (s *Server) getQuestionAnalytics(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	questions, err := s.questionStore.ListQuestions(r.Context(), store.QuestionFilter{CourseID: course.ID}, -1, 0)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	responses, err := s.quizStore.ListItemResponses(r.Context(), course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	stats := analyzeItems(questions, responses)
+	if r.URL.Query().Get("flagged") == "true" {
+		stats = slices.DeleteFunc(stats, func(item itemStats) bool { return len(item.Flags) == 0 })
+	}
+	utils.WriteJSONResponse(w, stats)
+}
@@ -2,89 +2,306 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
-	firebase "firebase.google.com/go"
-	"firebase.google.com/go/auth"
 	"github.com/gorilla/mux"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/auth"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/config"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/db"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/generator"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/metrics"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/telemetry"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils/logger"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/api/option"
 	"gorm.io/gorm"
 )
 
 type Server struct {
-	courseStore store.CourseStoreInterface
-	userStore   store.UserStoreInterface
-	quizStore   store.QuizStoreInterface
-	logger      *logrus.Logger
-	db          *gorm.DB
-	authClient  *auth.Client
+	courseStore     store.CourseStoreInterface
+	userStore       store.UserStoreInterface
+	quizStore       store.QuizStoreInterface
+	questionStore   store.QuestionStoreInterface
+	enrollmentStore store.EnrollmentStoreInterface
+	moduleStore     store.ModuleStoreInterface
+	logger          *logrus.Logger
+	db              *gorm.DB
+	authenticator   auth.Authenticator
+	metrics         *metrics.Metrics
+	generators      map[string]generator.QuizGenerator
+	quizGenerator   string        // name of the generator used when a request names none
+	shutdownTimeout time.Duration // how long requests in flight are waited for on shutdown
+	closers         []closer
+	migrated        atomic.Bool // set once the readiness probe found the migrations applied
+}
+
+// probePaths are served outside the api, they are not traced and only logged at debug level
+var probePaths = []string{"/healthz", "/readyz", "/metrics"}
+
+// log returns the logger of a request, its lines carry the request id, the user and the trace of the request
+func (s *Server) log(r *http.Request) *logrus.Entry {
+	return s.logger.WithContext(r.Context())
+}
+
+// closer releases a resource of the server on shutdown
+type closer struct {
+	name  string
+	close func() error
 }
 
 func NewServer() *Server {
-	logger := logger.NewLogger()
+	logger, err := logger.NewLogger(config.Envs.LogFormat, config.Envs.LogLevel)
+	if err != nil {
+		logrus.Fatalf("failed to set up logging: %v", err)
+	}
+	shutdownTracing, err := telemetry.Setup(context.Background(), config.Envs.TracesExporter, config.Envs.ServiceName)
+	if err != nil {
+		logger.Fatalf("failed to set up tracing: %v", err)
+	}
 	db, err := db.NewDB()
 	if err != nil {
 		logger.Fatalf("failed to connect to database: %v", err)
 	}
+	serverMetrics := metrics.New()
+	if err := db.Use(serverMetrics.GORMPlugin()); err != nil {
+		logger.Fatalf("failed to install the database metrics: %v", err)
+	}
+	if err := db.Use(telemetry.GORMPlugin()); err != nil {
+		logger.Fatalf("failed to install the database tracing: %v", err)
+	}
 	s := store.NewStore(db, logger)
 	courseStore := store.NewCourseStore(s)
 	userStore := store.NewUserStore(s)
 	quizStore := store.NewQuizStore(s)
+	questionStore := store.NewQuestionStore(s)
+	enrollmentStore := store.NewEnrollmentStore(s)
+	moduleStore := store.NewModuleStore(s)
 
-	// Initialize the Firebase SDK
-	// the name key.json is used but we can also get it from the env vars if needed
-	opt := option.WithCredentialsFile(config.Envs.GoogleConfigPath)
-	fbApp, err := firebase.NewApp(context.Background(), nil, opt)
+	authenticator, err := newAuthenticator(db)
 	if err != nil {
-		logger.Fatalf("error initializing firebase app: %v", err)
+		logger.Fatalf("error initializing %s auth provider: %v", config.Envs.AuthProvider, err)
 	}
-	authClient, err := fbApp.Auth(context.Background())
+	generators, err := newGenerators(questionStore)
 	if err != nil {
-		logger.Fatalf("error initializing firebase auth: %v", err)
+		logger.Fatalf("error initializing the quiz generators: %v", err)
+	}
+	if _, found := generators[config.Envs.QuizGenerator]; !found {
+		logger.Fatalf("quiz generator %q is unknown or not configured", config.Envs.QuizGenerator)
 	}
 
-	return &Server{
-		courseStore: courseStore,
-		userStore:   userStore,
-		quizStore:   quizStore,
-		logger:      logger,
-		db:          db,
-		authClient:  authClient,
+	server := &Server{
+		courseStore:     courseStore,
+		userStore:       userStore,
+		quizStore:       quizStore,
+		questionStore:   questionStore,
+		enrollmentStore: enrollmentStore,
+		moduleStore:     moduleStore,
+		logger:          logger,
+		db:              db,
+		authenticator:   authenticator,
+		metrics:         serverMetrics,
+		generators:      generators,
+		quizGenerator:   config.Envs.QuizGenerator,
+		shutdownTimeout: time.Duration(config.Envs.ShutdownTimeout) * time.Second,
+	}
+	// registered first so it is closed last, the spans of the requests drained are still flushed
+	server.onShutdown("tracing", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	})
+	server.onShutdown("database", func() error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	return server
+}
+
+// newAuthenticator builds the auth provider selected by config.Envs.AuthProvider
+func newAuthenticator(db *gorm.DB) (auth.Authenticator, error) {
+	switch config.Envs.AuthProvider {
+	case "firebase":
+		// the name key.json is used but we can also get it from the env vars if needed
+		return auth.NewFirebase(context.Background(), config.Envs.GoogleConfigPath)
+	case "local":
+		ttl := time.Duration(config.Envs.JWTExpiryMinutes) * time.Minute
+		return auth.NewLocal(db, config.Envs.JWTSecret, ttl)
+	default:
+		return nil, fmt.Errorf("unknown auth provider %q", config.Envs.AuthProvider)
+	}
+}
+
+// newGenerators builds the quiz generators, the llm one only when config.Envs.LLMURL is set
+func newGenerators(questionStore store.QuestionStoreInterface) (map[string]generator.QuizGenerator, error) {
+	generators := map[string]generator.QuizGenerator{
+		generator.Bank:     generator.NewBank(questionStore),
+		generator.Template: generator.NewTemplate(),
+	}
+	if config.Envs.LLMURL != "" {
+		timeout := time.Duration(config.Envs.LLMTimeout) * time.Second
+		// the quiz is still to be saved and written once the model answered
+		if timeout <= 0 || timeout >= writeTimeout {
+			return nil, fmt.Errorf("LLM_TIMEOUT_SECONDS must be between 1 and %d, the write timeout of the server", int(writeTimeout.Seconds())-1)
+		}
+		generators[generator.LLM] = generator.NewLLM(config.Envs.LLMURL, config.Envs.LLMAPIKey, config.Envs.LLMModel, timeout)
 	}
+	return generators, nil
 }
 
-func (s *Server) Run() {
+// routes returns the handler serving the api
+func (s *Server) routes() http.Handler {
 	r := mux.NewRouter()
+	// the router skips its middlewares when no route matches, these are measured on their own
+	r.NotFoundHandler = s.metrics.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteErrorResponse(w, "Route not found", http.StatusNotFound)
+	}))
+	r.MethodNotAllowedHandler = s.metrics.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}))
+	r.Use(s.metrics.Middleware) // first so the requests rejected by the other middlewares are counted too
+	r.Use(telemetry.Route)
 	r.Use(corsMiddleware) // Use cors middleware to prevent CORS errors
 
-	r.HandleFunc("/api/v1/register", s.registerUser).Methods("POST") // the auth endpoint
+	r.Handle("/api/v1/register", traced("registerUser", s.registerUser)).Methods("POST") // the auth endpoint
+	r.Handle("/api/v1/login", traced("loginUser", s.loginUser)).Methods("POST")
 	api := r.PathPrefix("/api/v1").Subrouter()
 
 	api.Use(s.authMiddleware)
 
-	api.Handle("/courses", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(http.HandlerFunc(s.getCourses))).Methods("GET")
-	api.Handle("/courses/quiz", RBACMiddleware(s.db, schema.Educator, schema.Admin)(http.HandlerFunc(s.getQuiz))).Methods("GET")
-	api.Handle("/courses", RBACMiddleware(s.db, schema.Educator, schema.Admin)(http.HandlerFunc(s.postCourse))).Methods("POST")
-	api.Handle("/courses", RBACMiddleware(s.db, schema.Educator, schema.Admin)(http.HandlerFunc(s.deleteCourse))).Methods("DELETE") // TODO: fix
-	api.Handle("/quiz/generate", RBACMiddleware(s.db, schema.Educator, schema.Admin)(http.HandlerFunc(s.generateQuiz))).Methods("POST")
-	// TODO: create endpoint for history of quiz
+	api.Handle("/courses", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getCourses", s.getCourses))).Methods("GET")
+	api.Handle("/courses/quiz", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getQuiz", s.getQuiz))).Methods("GET")
+	api.Handle("/courses", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("postCourse", s.postCourse))).Methods("POST")
+	api.Handle("/courses", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("deleteCourse", s.deleteCourse))).Methods("DELETE")
+	api.Handle("/courses/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("updateCourse", s.updateCourse))).Methods("PUT", "PATCH")
+	api.Handle("/courses/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("deleteCourse", s.deleteCourse))).Methods("DELETE")
+	api.Handle("/courses/{id}/enroll", RBACMiddleware(s.db, schema.Student)(traced("enrollCourse", s.enrollCourse))).Methods("POST")
+	api.Handle("/courses/{id}/enroll", RBACMiddleware(s.db, schema.Student)(traced("unenrollCourse", s.unenrollCourse))).Methods("DELETE")
+	api.Handle("/courses/{id}/enrollments", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("getRoster", s.getRoster))).Methods("GET")
+	api.Handle("/courses/{id}/enrollments", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("saveRosterEntry", s.saveRosterEntry))).Methods("POST")
+	api.Handle("/courses/{id}/enrollments/{user_id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("saveRosterEntry", s.saveRosterEntry))).Methods("PUT")
+	api.Handle("/courses/{id}/enrollments/{user_id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("deleteRosterEntry", s.deleteRosterEntry))).Methods("DELETE")
+	api.Handle("/courses/{id}/analytics/questions", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("getQuestionAnalytics", s.getQuestionAnalytics))).Methods("GET")
+	api.Handle("/courses/{id}/gradebook", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("getGradebook", s.getGradebook))).Methods("GET")
+	api.Handle("/enrollments", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getMyEnrollments", s.getMyEnrollments))).Methods("GET")
+	api.Handle("/courses/{id}/modules", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getModules", s.getModules))).Methods("GET")
+	api.Handle("/courses/{id}/modules", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("postModule", s.postModule))).Methods("POST")
+	api.Handle("/courses/{id}/modules/order", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("reorderModules", s.reorderModules))).Methods("PUT")
+	api.Handle("/modules/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("putModule", s.putModule))).Methods("PUT")
+	api.Handle("/modules/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("deleteModule", s.deleteModule))).Methods("DELETE")
+	api.Handle("/modules/{id}/lessons", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("postLesson", s.postLesson))).Methods("POST")
+	api.Handle("/modules/{id}/lessons/order", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("reorderLessons", s.reorderLessons))).Methods("PUT")
+	api.Handle("/lessons/{id}", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getLesson", s.getLesson))).Methods("GET")
+	api.Handle("/lessons/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("putLesson", s.putLesson))).Methods("PUT")
+	api.Handle("/lessons/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("deleteLesson", s.deleteLesson))).Methods("DELETE")
+	api.Handle("/courses/{id}/questions", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("getQuestions", s.getQuestions))).Methods("GET")
+	api.Handle("/courses/{id}/questions", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("postQuestion", s.postQuestion))).Methods("POST")
+	api.Handle("/courses/{id}/questions/import", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("importQuestions", s.importQuestions))).Methods("POST")
+	api.Handle("/courses/{id}/questions/export", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("exportQuestions", s.exportQuestions))).Methods("GET")
+	api.Handle("/questions/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("putQuestion", s.putQuestion))).Methods("PUT")
+	api.Handle("/questions/{id}", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("deleteQuestion", s.deleteQuestion))).Methods("DELETE")
+	api.Handle("/quiz/generate", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("generateQuiz", s.generateQuiz))).Methods("POST")
+	api.Handle("/quiz/start", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("startQuiz", s.startQuiz))).Methods("POST")
+	api.Handle("/quiz/{id}/settings", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("putQuizSettings", s.putQuizSettings))).Methods("PUT")
+	api.Handle("/quiz/{id}/points", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("putQuizPoints", s.putQuizPoints))).Methods("PUT")
+	api.Handle("/quiz/{id}/export", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("exportQuiz", s.exportQuiz))).Methods("GET")
+	api.Handle("/quiz/{id}/next", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getNextQuestion", s.getNextQuestion))).Methods("GET")
+	api.Handle("/quiz/{id}/answer", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("answerQuestion", s.answerQuestion))).Methods("POST")
+	api.Handle("/quiz/submit", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("submitQuiz", s.submitQuiz))).Methods("POST")
+	api.Handle("/quiz/history", RBACMiddleware(s.db, schema.Student, schema.Educator, schema.Admin)(traced("getQuizHistory", s.getQuizHistory))).Methods("GET")
+	api.Handle("/quiz/attempts", RBACMiddleware(s.db, schema.Educator, schema.Admin)(traced("getQuizAttempts", s.getQuizAttempts))).Methods("GET")
 
 	rateLimitMiddleware := s.newRateLimitMiddleware()
 	r.Use(rateLimitMiddleware)
 
-	s.logger.Info("Server is running on port 8080")
+	// The probes and the metrics are served before the rate limiting and the authentication of the api
+	root := mux.NewRouter()
+	root.HandleFunc("/healthz", s.getHealth).Methods("GET")
+	root.HandleFunc("/readyz", s.getReady).Methods("GET")
+	root.HandleFunc("/version", s.getVersion).Methods("GET")
+	root.Handle("/metrics", s.metrics.Handler()).Methods("GET")
+	root.PathPrefix("/").Handler(r)
+
+	// outside the router so unmatched routes get an id and an access log too, and inside the server span so it is recorded on it
+	return telemetry.Handler(requestIDMiddleware(s.accessLogMiddleware(root)), probePaths...)
+}
+
+// writeTimeout is how long a request has to be served, its response is cut off past it
+const writeTimeout = 15 * time.Second
+
+// Run serves the api until ctx is done, then shuts the server down gracefully
+// It only returns early if the server fails to listen or serve
+func (s *Server) Run(ctx context.Context) error {
 	server := &http.Server{
-		Handler:      r,
+		Handler:      s.routes(),
 		Addr:         config.Envs.PublicHost + ":" + config.Envs.Port,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: writeTimeout,
 		ReadTimeout:  15 * time.Second,
 	}
-	s.logger.Fatal(server.ListenAndServe())
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to listen on %s: %w", server.Addr, err), s.close())
+	}
+	return s.serve(ctx, server, listener)
+}
+
+// serve runs the server on the listener until ctx is done
+// The requests in flight then get up to shutdownTimeout to complete before the resources of the server are closed
+func (s *Server) serve(ctx context.Context, server *http.Server, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	s.logger.Infof("Server is listening on %s", listener.Addr())
+
+	select {
+	case err := <-serveErr:
+		return errors.Join(fmt.Errorf("server failed: %w", err), s.close())
+	case <-ctx.Done():
+	}
+
+	s.logger.Infof("Shutting down, waiting up to %s for requests in flight", s.shutdownTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	var drainErr error
+	if err := server.Shutdown(drainCtx); err != nil {
+		// the requests still running are cut off so the database is not closed under them
+		drainErr = fmt.Errorf("failed to drain requests: %w", err)
+		server.Close()
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		drainErr = errors.Join(drainErr, err)
+	}
+	err := errors.Join(drainErr, s.close())
+	if err == nil {
+		s.logger.Info("Server stopped")
+	}
+	return err
+}
+
+// onShutdown registers a function releasing a resource of the server once it stopped serving
+// They are called in the reverse order of their registration
+func (s *Server) onShutdown(name string, close func() error) {
+	s.closers = append(s.closers, closer{name: name, close: close})
+}
+
+// close releases the resources of the server, reporting every failure
+func (s *Server) close() error {
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", s.closers[i].name, err))
+		}
+	}
+	s.closers = nil
+	return errors.Join(errs...)
 }
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// submitGrace is how long after its deadline a submission still counts as on time
+// It makes up for the latency between the client and the server
+const submitGrace = 30 * time.Second
+
+// Errors returned when the settings of a quiz do not allow an attempt, reported as 403
+var (
+	errQuizNotOpen     = errors.New("the quiz is not open yet")
+	errQuizClosed      = errors.New("the quiz is closed")
+	errNoAttemptsLeft  = errors.New("no attempts left for this quiz")
+	errPastDeadline    = errors.New("the attempt is past its deadline, late submissions are not accepted")
+	errAttemptRefusals = []error{errQuizNotOpen, errQuizClosed, errNoAttemptsLeft, errPastDeadline}
+)
+
+// writeAttemptError writes a 403 if the settings of the quiz refused the attempt and a 500 otherwise
+func writeAttemptError(w http.ResponseWriter, err error) {
+	for _, refusal := range errAttemptRefusals {
+		if errors.Is(err, refusal) {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// attemptSettings returns the settings enforced on the attempts of the given role
+// Educators and admins only preview quizzes, so nothing is enforced on them
+func attemptSettings(quiz *schema.Quiz, role schema.Role) schema.QuizSettings {
+	if canSeeAnswers(role) {
+		return schema.QuizSettings{Scoring: quiz.Settings.Scoring}
+	}
+	return quiz.Settings
+}
+
+// attemptDeadline returns when an attempt started at start has to be submitted, nil if never
+// The time limit does not extend an attempt past the closing of the quiz
+func attemptDeadline(settings schema.QuizSettings, start time.Time) *time.Time {
+	var deadline *time.Time
+	if settings.TimeLimitMinutes > 0 {
+		d := start.Add(time.Duration(settings.TimeLimitMinutes) * time.Minute)
+		deadline = &d
+	}
+	if settings.ClosesAt != nil && (deadline == nil || settings.ClosesAt.Before(*deadline)) {
+		d := *settings.ClosesAt
+		deadline = &d
+	}
+	return deadline
+}
+
+// pastDeadline reports if the attempt is submitted too late at now
+func pastDeadline(attempt *schema.QuizAttempt, now time.Time) bool {
+	return attempt.Deadline != nil && now.After(attempt.Deadline.Add(submitGrace))
+}
+
+// newAttempt returns a new attempt of the user at now, if the settings allow one
+// The attempt is not saved
+func (s *Server) newAttempt(ctx context.Context, quiz *schema.Quiz, settings schema.QuizSettings, userID uint, now time.Time) (*schema.QuizAttempt, error) {
+	if settings.OpensAt != nil && now.Before(*settings.OpensAt) {
+		return nil, errQuizNotOpen
+	}
+	if settings.ClosesAt != nil && !now.Before(*settings.ClosesAt) {
+		return nil, errQuizClosed
+	}
+	if settings.MaxAttempts > 0 {
+		submitted, err := s.quizStore.CountSubmittedAttempts(ctx, userID, quiz.ID)
+		if err != nil {
+			return nil, err
+		}
+		if submitted >= int64(settings.MaxAttempts) {
+			return nil, errNoAttemptsLeft
+		}
+	}
+	return &schema.QuizAttempt{UserID: userID, QuizID: quiz.ID, StartedAt: now, Deadline: attemptDeadline(settings, now)}, nil
+}
+
+// closeExpiredAttempt submits an attempt which ran out of time without any answers
+// Adaptive attempts keep the answers given before the deadline
+func (s *Server) closeExpiredAttempt(ctx context.Context, quiz *schema.Quiz, attempt *schema.QuizAttempt) error {
+	if quiz.Adaptive {
+		closeAdaptiveAttempt(attempt, quiz)
+	} else {
+		gradeAttempt(attempt, quiz.Questions, nil, quiz.Settings.Scoring)
+	}
+	attempt.SubmittedAt = attempt.Deadline
+	return s.submitAttempt(ctx, quiz, attempt)
+}
+
+// submitAttempt saves a graded attempt of the quiz as submitted
+func (s *Server) submitAttempt(ctx context.Context, quiz *schema.Quiz, attempt *schema.QuizAttempt) error {
+	if err := s.quizStore.SubmitAttempt(ctx, attempt); err != nil {
+		return err
+	}
+	s.metrics.AttemptSubmitted(quiz.Adaptive, attempt.Late)
+	return nil
+}
+
+// beginAttempt returns the attempt in progress of the user, starting one if there is none
+// An attempt past its deadline is closed first when late submissions are rejected
+func (s *Server) beginAttempt(ctx context.Context, quiz *schema.Quiz, settings schema.QuizSettings, userID uint, seed int64, now time.Time) (*schema.QuizAttempt, error) {
+	attempt, err := s.quizStore.GetOpenAttempt(ctx, userID, quiz.ID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt != nil && pastDeadline(attempt, now) && lateRejected(settings) {
+		if err := s.closeExpiredAttempt(ctx, quiz, attempt); err != nil {
+			return nil, err
+		}
+		attempt = nil
+	}
+	if attempt != nil {
+		return attempt, nil
+	}
+
+	if attempt, err = s.newAttempt(ctx, quiz, settings, userID, now); err != nil {
+		return nil, err
+	}
+	attempt.VariantSeed = seed
+	if err := s.quizStore.StartAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func lateRejected(settings schema.QuizSettings) bool {
+	return settings.LatePolicy != schema.LateAccept && settings.LatePolicy != schema.LatePenalize
+}
+
+// Handler to start an attempt of a quiz
+// The attempt in progress is returned if there is one, its deadline tells the client how long is left
+func (s *Server) startQuiz(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		QuizID uint `json:"quiz_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.QuizID == 0 {
+		utils.WriteErrorResponse(w, "quiz_id is required", http.StatusBadRequest)
+		return
+	}
+
+	quiz, err := s.quizStore.GetQuizById(r.Context(), req.QuizID)
+	if err != nil {
+		writeStoreError(w, err, "quiz")
+		return
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+	if !s.requireEnrollment(w, r, user, quiz.CourseID) {
+		return
+	}
+
+	role := roleFromContext(r)
+	seed := int64(0)
+	if (quiz.PerStudentVariants && !canSeeAnswers(role)) || quiz.Adaptive {
+		seed = variantSeed(quiz.Seed, user.ID)
+	}
+	attempt, err := s.beginAttempt(r.Context(), quiz, attemptSettings(quiz, role), user.ID, seed, time.Now())
+	if err != nil {
+		writeAttemptError(w, err)
+		return
+	}
+	utils.WriteJSONResponse(w, attempt)
+}
+
+// validateSettings checks the settings of a quiz, normalizing the late policy
+func validateSettings(settings *schema.QuizSettings) error {
+	if settings.OpensAt != nil && settings.ClosesAt != nil && !settings.ClosesAt.After(*settings.OpensAt) {
+		return errors.New("closes_at must be after opens_at")
+	}
+	if settings.TimeLimitMinutes < 0 {
+		return errors.New("time_limit_minutes must not be negative")
+	}
+	if settings.MaxAttempts < 0 {
+		return errors.New("max_attempts must not be negative")
+	}
+
+	settings.LatePolicy = schema.LatePolicy(strings.ToUpper(string(settings.LatePolicy)))
+	switch settings.LatePolicy {
+	case "":
+		settings.LatePolicy = schema.LateReject
+	case schema.LateReject, schema.LateAccept, schema.LatePenalize:
+	default:
+		return fmt.Errorf("invalid late_policy, must be one of %s, %s or %s", schema.LateReject, schema.LateAccept, schema.LatePenalize)
+	}
+	if settings.LatePenalty < 0 || settings.LatePenalty > 1 {
+		return errors.New("late_penalty must be between 0 and 1")
+	}
+	if settings.LatePenalty != 0 && settings.LatePolicy != schema.LatePenalize {
+		return fmt.Errorf("late_penalty is only used with the %s late_policy", schema.LatePenalize)
+	}
+	if settings.Scoring.NegativeMarking < 0 || settings.Scoring.NegativeMarking > 1 {
+		return errors.New("scoring.negative_marking must be between 0 and 1")
+	}
+	if settings.Scoring.PassingScore < 0 || settings.Scoring.PassingScore > 1 {
+		return errors.New("scoring.passing_score must be between 0 and 1")
+	}
+	return nil
+}
+
+// authorizeQuizOwner loads the quiz, writing an error unless the current user can modify its course
+func (s *Server) authorizeQuizOwner(w http.ResponseWriter, r *http.Request, id uint) (*schema.Quiz, bool) {
+	quiz, err := s.quizStore.GetQuizById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "quiz")
+		return nil, false
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), quiz.CourseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return nil, false
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return nil, false
+	}
+	return quiz, true
+}
+
+// Handler to replace the settings of a quiz
+func (s *Server) putQuizSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var settings schema.QuizSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateSettings(&settings); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	quiz, ok := s.authorizeQuizOwner(w, r, id)
+	if !ok {
+		return
+	}
+
+	quiz.Settings = settings
+	if err := s.quizStore.UpdateQuizSettings(r.Context(), quiz); err != nil {
+		utils.WriteErrorResponse(w, "failed to update quiz settings", http.StatusInternalServerError)
+		return
+	}
+	s.writeQuizResponse(w, r, quiz)
+}
+
+// Handler to set the points every question of a quiz is worth
+// points[i] is the weight of the i-th question of the quiz
+func (s *Server) putQuizPoints(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Points []float64 `json:"points"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, points := range req.Points {
+		if points <= 0 {
+			utils.WriteErrorResponse(w, "points must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+
+	quiz, ok := s.authorizeQuizOwner(w, r, id)
+	if !ok {
+		return
+	}
+	if len(req.Points) != len(quiz.Questions) {
+		utils.WriteErrorDetails(w, "points must give the weight of every question", http.StatusBadRequest,
+			map[string]int{"questions": len(quiz.Questions), "points": len(req.Points)})
+		return
+	}
+
+	for i := range quiz.Questions {
+		quiz.Questions[i].Points = req.Points[i]
+	}
+	if err := s.quizStore.UpdateQuizPoints(r.Context(), quiz); err != nil {
+		utils.WriteErrorResponse(w, "failed to update quiz points", http.StatusInternalServerError)
+		return
+	}
+	s.writeQuizResponse(w, r, quiz)
+}
@@ -1,12 +1,13 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
-	"firebase.google.com/go/auth"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/auth"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
 )
 
 // Handler to register new users
@@ -19,39 +20,82 @@ func (s *Server) registerUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 	if req.Email == "" || req.Password == "" {
-		http.Error(w, "Email and password are required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Email and password are required", http.StatusBadRequest)
 		return
 	}
 	if schema.Role(req.Role) != schema.Student && schema.Role(req.Role) != schema.Educator {
-		http.Error(w, "Invalid role", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Invalid role", http.StatusBadRequest)
 		return
 	}
 
-	params := (&auth.UserToCreate{}).Email(req.Email).Password(req.Password)
-	userRecord, err := s.authClient.CreateUser(context.Background(), params)
+	userRecord, err := s.authenticator.CreateUser(r.Context(), req.Email, req.Password)
 	if err != nil {
-		http.Error(w, "Error creating user: "+err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, auth.ErrEmailTaken) {
+			utils.WriteErrorResponse(w, "Error creating user: "+err.Error(), http.StatusConflict)
+			return
+		}
+		s.log(r).Errorf("Failed to create user with the auth provider: %v", err)
+		utils.WriteErrorResponse(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 
-	s.logger.Infof("Registered a new user %+v", userRecord.UserInfo)
+	s.log(r).Infof("Registered a new user %+v", *userRecord)
 
 	dbUser := &schema.User{
-		UID:   userRecord.UserInfo.UID,
-		Email: userRecord.UserInfo.Email,
-		Name:  userRecord.UserInfo.DisplayName,
+		UID:   userRecord.UID,
+		Email: userRecord.Email,
+		Name:  userRecord.DisplayName,
 		Role:  schema.Role(req.Role),
 	}
-	err = s.userStore.CreateUser(dbUser)
+	err = s.userStore.CreateUser(r.Context(), dbUser)
 	if err != nil {
-		http.Error(w, "Error creating user: "+err.Error(), http.StatusInternalServerError)
+		writeStoreError(w, err, "user")
 		return
 	}
+	s.metrics.UserRegistered(req.Role)
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(userRecord)
 }
+
+// Handler to sign in with the local auth provider
+// Firebase users get their token from google instead
+func (s *Server) loginUser(w http.ResponseWriter, r *http.Request) {
+	issuer, ok := s.authenticator.(auth.TokenIssuer)
+	if !ok {
+		utils.WriteErrorResponse(w, "Login is handled by the identity provider", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		utils.WriteErrorResponse(w, "Email and password are required", http.StatusBadRequest)
+		return
+	}
+
+	token, err := issuer.SignIn(r.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			utils.WriteErrorResponse(w, "Invalid email or password", http.StatusUnauthorized)
+			return
+		}
+		s.log(r).Errorf("Sign in failed: %v", err)
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
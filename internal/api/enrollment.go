@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+func parseEnrollmentStatus(value string) (schema.EnrollmentStatus, error) {
+	status := schema.EnrollmentStatus(strings.ToUpper(value))
+	switch status {
+	case schema.EnrollmentActive, schema.EnrollmentDropped, schema.EnrollmentCompleted:
+		return status, nil
+	}
+	return "", errors.New("status must be one of ACTIVE, DROPPED or COMPLETED")
+}
+
+// requireEnrollment writes a 403 unless the user can take the quizzes of the course
+// Students need an active enrollment, educators must own the course and admins always can
+func (s *Server) requireEnrollment(w http.ResponseWriter, r *http.Request, user *schema.User, courseID uint) bool {
+	if user.Role != schema.Student {
+		course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+		if err != nil {
+			writeStoreError(w, err, "course")
+			return false
+		}
+		if !canModifyCourse(user, course) {
+			utils.WriteErrorResponse(w, "Forbidden: only the creator of the course can see its quizzes and lessons", http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+	enrollment, err := s.enrollmentStore.GetEnrollment(r.Context(), user.ID, courseID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if enrollment == nil || enrollment.Status != schema.EnrollmentActive {
+		utils.WriteErrorResponse(w, "Forbidden: you are not enrolled in this course", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// listEnrollments writes the enrollments matching the filter, honouring the status, limit and offset query params
+func (s *Server) listEnrollments(w http.ResponseWriter, r *http.Request, filter store.EnrollmentFilter) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q := r.URL.Query().Get("status"); q != "" {
+		if filter.Status, err = parseEnrollmentStatus(q); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	enrollments, err := s.enrollmentStore.ListEnrollments(r.Context(), filter, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, enrollments)
+}
+
+// Handler for a student to enroll in a course
+// Enrolling again after dropping a course reactivates the enrollment
+func (s *Server) enrollCourse(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+
+	enrollment, err := s.enrollmentStore.GetEnrollment(r.Context(), user.ID, course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enrollment == nil {
+		enrollment = &schema.Enrollment{UserID: user.ID, CourseID: course.ID}
+	} else if enrollment.Status != schema.EnrollmentDropped {
+		utils.WriteErrorResponse(w, "already enrolled in this course", http.StatusConflict)
+		return
+	}
+	enrollment.Status = schema.EnrollmentActive
+
+	if err := s.enrollmentStore.SaveEnrollment(r.Context(), enrollment); err != nil {
+		utils.WriteErrorResponse(w, "failed to enroll", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, enrollment)
+}
+
+// Handler for a student to drop a course
+func (s *Server) unenrollCourse(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+
+	enrollment, err := s.enrollmentStore.GetEnrollment(r.Context(), user.ID, courseID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enrollment == nil || enrollment.Status != schema.EnrollmentActive {
+		utils.WriteErrorResponse(w, "not enrolled in this course", http.StatusNotFound)
+		return
+	}
+	enrollment.Status = schema.EnrollmentDropped
+
+	if err := s.enrollmentStore.SaveEnrollment(r.Context(), enrollment); err != nil {
+		utils.WriteErrorResponse(w, "failed to unenroll", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, enrollment)
+}
+
+// Handler to list the enrollments of the current user
+func (s *Server) getMyEnrollments(w http.ResponseWriter, r *http.Request) {
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+	s.listEnrollments(w, r, store.EnrollmentFilter{UserID: user.ID})
+}
+
+// Handler to list the roster of a course
+func (s *Server) getRoster(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+	s.listEnrollments(w, r, store.EnrollmentFilter{CourseID: course.ID})
+}
+
+// Handler for the owner of a course to add a student to the roster or change their status
+// POST adds a student (status defaults to ACTIVE), PUT changes the status of an existing enrollment
+func (s *Server) saveRosterEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		UserID uint   `json:"user_id"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPut {
+		if req.UserID, err = pathID(r, "user_id"); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Status == "" {
+			utils.WriteErrorResponse(w, "status is required", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.UserID == 0 {
+		utils.WriteErrorResponse(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	status := schema.EnrollmentActive
+	if req.Status != "" {
+		if status, err = parseEnrollmentStatus(req.Status); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+	student, err := s.userStore.GetUserById(r.Context(), req.UserID)
+	if err != nil {
+		writeStoreError(w, err, "user")
+		return
+	}
+	if student.Role != schema.Student {
+		utils.WriteErrorResponse(w, "only students can be enrolled", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := s.enrollmentStore.GetEnrollment(r.Context(), student.ID, course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enrollment == nil {
+		if r.Method == http.MethodPut {
+			utils.WriteErrorResponse(w, "enrollment not found", http.StatusNotFound)
+			return
+		}
+		enrollment = &schema.Enrollment{UserID: student.ID, CourseID: course.ID}
+	}
+	enrollment.Status = status
+
+	if err := s.enrollmentStore.SaveEnrollment(r.Context(), enrollment); err != nil {
+		utils.WriteErrorResponse(w, "failed to save enrollment", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, enrollment)
+}
+
+// Handler for the owner of a course to drop a student from the roster
+func (s *Server) deleteRosterEntry(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	enrollment, err := s.enrollmentStore.GetEnrollment(r.Context(), userID, course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enrollment == nil {
+		utils.WriteErrorResponse(w, "enrollment not found", http.StatusNotFound)
+		return
+	}
+	enrollment.Status = schema.EnrollmentDropped
+
+	if err := s.enrollmentStore.SaveEnrollment(r.Context(), enrollment); err != nil {
+		utils.WriteErrorResponse(w, "failed to save enrollment", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, enrollment)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// writeStoreError writes the response for an error returned by a store
+// resource names what was looked up, e.g. "course" gives "course not found"
+// Only the sentinel errors of the store are reported as client errors, anything else is a 500
+func writeStoreError(w http.ResponseWriter, err error, resource string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		utils.WriteErrorResponse(w, resource+" not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrConflict):
+		utils.WriteErrorResponse(w, resource+" already exists", http.StatusConflict)
+	case errors.Is(err, store.ErrValidation):
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeCurrentUserError writes the response when the user making the request can not be loaded
+func writeCurrentUserError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		utils.WriteErrorResponse(w, "user not found", http.StatusUnauthorized)
+		return
+	}
+	utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+func parseGradePolicy(value string) (schema.GradePolicy, error) {
+	policy := schema.GradePolicy(strings.ToUpper(value))
+	switch policy {
+	case schema.GradeBest, schema.GradeLatest, schema.GradeAverage:
+		return policy, nil
+	}
+	return "", errors.New("grade_policy must be one of BEST, LATEST or AVERAGE")
+}
+
+// gradebook is the grade of every student of a course in every quiz of the course
+type gradebook struct {
+	CourseID uint               `json:"course_id"`
+	Policy   schema.GradePolicy `json:"policy"`
+	Quizzes  []gradebookQuiz    `json:"quizzes"`
+	Students []gradebookRow     `json:"students"`
+}
+
+type gradebookQuiz struct {
+	ID       uint    `json:"id"`
+	LessonID *uint   `json:"lesson_id"`
+	MaxScore float64 `json:"max_score"`
+}
+
+type gradebookRow struct {
+	UserID   uint                    `json:"user_id"`
+	Name     string                  `json:"name"`
+	Email    string                  `json:"email"`
+	Status   schema.EnrollmentStatus `json:"status"`
+	Grades   []*quizGrade            `json:"grades"` // Grades[i] is the grade in Quizzes[i], null if never attempted
+	Total    float64                 `json:"total"`
+	MaxTotal float64                 `json:"max_total"`
+	Percent  float64                 `json:"percent"`
+}
+
+type quizGrade struct {
+	QuizID   uint    `json:"quiz_id"`
+	Score    float64 `json:"score"`
+	MaxScore float64 `json:"max_score"`
+	Attempts int     `json:"attempts"`
+}
+
+// quizMaxScore returns the points a quiz is scored out of
+// Adaptive attempts are only scored out of the questions they served, so an adaptive quiz
+// is worth the mean points of its questions times its length
+func quizMaxScore(quiz *schema.Quiz) float64 {
+	total := 0.0
+	for _, qq := range quiz.Questions {
+		total += qq.Points
+	}
+	if quiz.Adaptive && len(quiz.Questions) > 0 {
+		return total / float64(len(quiz.Questions)) * float64(adaptiveLength(quiz))
+	}
+	return total
+}
+
+// buildGradebook aggregates the submitted attempts of every enrolled student with the policy
+// Quizzes a student never attempted count as 0 towards their total and the max score of
+// the quiz towards their max total, the others as the max score of their grade
+func buildGradebook(courseID uint, policy schema.GradePolicy, quizzes []schema.Quiz, enrollments []schema.Enrollment, attempts []schema.QuizAttempt) gradebook {
+	book := gradebook{CourseID: courseID, Policy: policy, Quizzes: make([]gradebookQuiz, len(quizzes)), Students: []gradebookRow{}}
+	column := map[uint]int{}
+	for i := range quizzes {
+		book.Quizzes[i] = gradebookQuiz{ID: quizzes[i].ID, LessonID: quizzes[i].LessonID, MaxScore: quizMaxScore(&quizzes[i])}
+		column[quizzes[i].ID] = i
+	}
+
+	row := map[uint]int{}
+	for _, e := range enrollments {
+		row[e.UserID] = len(book.Students)
+		book.Students = append(book.Students, gradebookRow{
+			UserID: e.UserID,
+			Name:   e.User.Name,
+			Email:  e.User.Email,
+			Status: e.Status,
+			Grades: make([]*quizGrade, len(quizzes)),
+		})
+	}
+
+	for _, a := range attempts {
+		r, enrolled := row[a.UserID]
+		c, inCourse := column[a.QuizID]
+		if !enrolled || !inCourse || a.SubmittedAt == nil {
+			continue
+		}
+		grade := book.Students[r].Grades[c]
+		if grade == nil {
+			grade = &quizGrade{QuizID: a.QuizID}
+			book.Students[r].Grades[c] = grade
+		}
+		grade.Attempts++
+		switch policy {
+		case schema.GradeLatest:
+			// attempts are listed latest first
+			if grade.Attempts == 1 {
+				grade.Score, grade.MaxScore = a.Score, a.MaxScore
+			}
+		case schema.GradeAverage:
+			n := float64(grade.Attempts)
+			grade.Score += (a.Score - grade.Score) / n
+			grade.MaxScore += (a.MaxScore - grade.MaxScore) / n
+		default:
+			if grade.Attempts == 1 || a.Score > grade.Score {
+				grade.Score, grade.MaxScore = a.Score, a.MaxScore
+			}
+		}
+	}
+
+	for i := range book.Students {
+		student := &book.Students[i]
+		for c, grade := range student.Grades {
+			if grade != nil {
+				student.Total += grade.Score
+				student.MaxTotal += grade.MaxScore
+			} else {
+				student.MaxTotal += book.Quizzes[c].MaxScore
+			}
+		}
+		if student.MaxTotal > 0 {
+			student.Percent = math.Round(student.Total/student.MaxTotal*10000) / 100
+		}
+	}
+	return book
+}
+
+// Handler to get the gradebook of a course
+// The policy of the course is used unless the policy query param is given, format=csv exports it as CSV
+func (s *Server) getGradebook(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "csv" {
+		utils.WriteErrorResponse(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+	policy := course.GradePolicy
+	if q := r.URL.Query().Get("policy"); q != "" {
+		if policy, err = parseGradePolicy(q); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if policy == "" {
+		policy = schema.GradeBest
+	}
+
+	quizzes, err := s.quizStore.ListCourseQuizzes(r.Context(), course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	enrollments, err := s.enrollmentStore.ListEnrollments(r.Context(), store.EnrollmentFilter{CourseID: course.ID}, -1, 0)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	attempts, err := s.quizStore.ListAttempts(r.Context(), store.AttemptFilter{CourseID: course.ID}, -1, 0)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	book := buildGradebook(course.ID, policy, quizzes, enrollments, attempts)
+	if format == "csv" {
+		s.writeGradebookCSV(w, r, book)
+		return
+	}
+	utils.WriteJSONResponse(w, book)
+}
+
+// writeGradebookCSV writes one row per student with a score column per quiz
+func (s *Server) writeGradebookCSV(w http.ResponseWriter, r *http.Request, book gradebook) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="course-%d-gradebook.csv"`, book.CourseID))
+
+	out := csv.NewWriter(w)
+	header := []string{"user_id", "name", "email", "status"}
+	for _, quiz := range book.Quizzes {
+		header = append(header, fmt.Sprintf("quiz %d (/%s)", quiz.ID, formatScore(quiz.MaxScore)))
+	}
+	out.Write(append(header, "total", "max_total", "percent"))
+
+	for _, student := range book.Students {
+		record := []string{strconv.FormatUint(uint64(student.UserID), 10), csvSafe(student.Name), csvSafe(student.Email), string(student.Status)}
+		for _, grade := range student.Grades {
+			if grade == nil {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, formatScore(grade.Score))
+		}
+		out.Write(append(record, formatScore(student.Total), formatScore(student.MaxTotal), formatScore(student.Percent)))
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		s.log(r).Errorf("Failed to write gradebook of course %d: %v", book.CourseID, err)
+	}
+}
+
+// formatScore rounds the score to 2 decimals, averages can have many more
+func formatScore(score float64) string {
+	return strconv.FormatFloat(math.Round(score*100)/100, 'f', -1, 64)
+}
+
+// csvSafe keeps spreadsheets from evaluating user provided text as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"math/rand"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+)
+
+// shuffleQuestion shuffles what the student picks from without changing the meaning of the answer
+// Matching questions keep their prompts in place since answers are given in the order of the prompts
+func shuffleQuestion(rng *rand.Rand, q *schema.Question) {
+	switch scoring.Type(q) {
+	case schema.SingleChoice, schema.MultiSelect, schema.Ordering:
+		q.Options = shuffled(rng, q.Options)
+	case schema.Matching:
+		q.Matches = shuffled(rng, q.Matches)
+	}
+}
+
+// gradeAttempt grades the answers of the attempt with the scoring rules of the quiz
+// answers[i] is the answer to questions[i], missing answers are graded as unanswered
+func gradeAttempt(attempt *schema.QuizAttempt, questions []schema.QuizQuestion, answers []scoring.Response, rules schema.ScoringRules) {
+	grade := scoring.GradeQuiz(questions, answers, rules)
+	attempt.Answers = make([]schema.AttemptAnswer, len(questions))
+	for i, qq := range questions {
+		var answer scoring.Response
+		if i < len(answers) {
+			answer = answers[i]
+		}
+		result := grade.Results[i]
+		attempt.Answers[i] = schema.AttemptAnswer{Position: qq.Position, Points: result.Points, Correct: result.Correct()}
+		if scoring.IsList(&qq.Question) {
+			attempt.Answers[i].Answers = answer
+		} else {
+			attempt.Answers[i].Answer = answer.Single()
+		}
+	}
+	attempt.Score, attempt.MaxScore = grade.Score, grade.MaxScore
+	attempt.Passed = scoring.Passed(rules, attempt.Score, attempt.MaxScore)
+}
@@ -2,16 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
 )
 
-func (s *Server) getCourses(w http.ResponseWriter, r *http.Request) {
-	s.logger.Info("/GET /api/v1/courses")
-
+// parsePagination reads the limit and offset query params, defaulting to 10 and 0
+func parsePagination(r *http.Request) (int, int, error) {
 	limitQuery := r.URL.Query().Get("limit")
 	offsetQuery := r.URL.Query().Get("offset")
 	if limitQuery == "" {
@@ -23,15 +24,22 @@ func (s *Server) getCourses(w http.ResponseWriter, r *http.Request) {
 
 	limit, err := strconv.Atoi(limitQuery)
 	if err != nil {
-		utils.WriteErrorResponse(w, "Invalid limit, limit must be a number", http.StatusBadRequest)
-		return
+		return 0, 0, errors.New("Invalid limit, limit must be a number")
 	}
 	offset, err := strconv.Atoi(offsetQuery)
 	if err != nil {
-		utils.WriteErrorResponse(w, "Invalid offset, offset must be a number", http.StatusBadRequest)
+		return 0, 0, errors.New("Invalid offset, offset must be a number")
+	}
+	return limit, offset, nil
+}
+
+func (s *Server) getCourses(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	courses, err := s.courseStore.ListCourses(limit, offset)
+	courses, err := s.courseStore.ListCourses(r.Context(), limit, offset)
 	if err != nil {
 		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -61,19 +69,26 @@ func (s *Server) postCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if course.ID != 0 {
-		// Update the course instead of creating a new one
+		utils.WriteErrorResponse(w, "id must not be set, use PUT /api/v1/courses/{id} to update a course", http.StatusBadRequest)
+		return
+	}
+	if course.GradePolicy == "" {
+		course.GradePolicy = schema.GradeBest
+	} else if course.GradePolicy, err = parseGradePolicy(string(course.GradePolicy)); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	// Look up the user in db
 	uid, _ := r.Context().Value("userID").(string)
-	user, err := s.userStore.GetUserByUID(uid)
+	user, err := s.userStore.GetUserByUID(r.Context(), uid)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusUnauthorized)
+		writeCurrentUserError(w, err)
 		return
 	}
 	course.User = *user
 
-	if err := s.courseStore.CreateCourse(&course); err != nil {
-		s.logger.Error("Failed to create course", err)
+	if err := s.courseStore.CreateCourse(r.Context(), &course); err != nil {
+		s.log(r).WithError(err).Error("Failed to create course")
 		utils.WriteErrorResponse(w, "failed to create course", http.StatusInternalServerError)
 		return
 	}
@@ -81,8 +96,93 @@ func (s *Server) postCourse(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSONResponse(w, course)
 }
 
+// canModifyCourse reports if the user is allowed to edit or delete the course
+// Only the creator of a course or an admin can
+func canModifyCourse(user *schema.User, course *schema.Course) bool {
+	return user.Role == schema.Admin || course.UserID == user.ID
+}
+
+// authorizeCourseOwner writes a 403 unless the current user can modify the course
+func (s *Server) authorizeCourseOwner(w http.ResponseWriter, r *http.Request, course *schema.Course) bool {
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return false
+	}
+	if !canModifyCourse(user, course) {
+		utils.WriteErrorResponse(w, "Forbidden: only the creator of the course can modify it", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Handler to update a course
+// PUT replaces the editable fields while PATCH only changes the ones given
+func (s *Server) updateCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Title       *string `json:"title"`
+		GradePolicy *string `json:"grade_policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPut && req.Title == nil {
+		utils.WriteErrorResponse(w, "title is required", http.StatusBadRequest)
+		return
+	}
+	if req.Title != nil && *req.Title == "" {
+		utils.WriteErrorResponse(w, "title must not be empty", http.StatusBadRequest)
+		return
+	}
+	policy := schema.GradeBest
+	if req.GradePolicy != nil {
+		if policy, err = parseGradePolicy(*req.GradePolicy); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	if req.Title != nil {
+		course.Title = *req.Title
+	}
+	if r.Method == http.MethodPut || req.GradePolicy != nil {
+		course.GradePolicy = policy
+	}
+	if err := s.courseStore.UpdateCourse(r.Context(), course); err != nil {
+		utils.WriteErrorResponse(w, "failed to update course", http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJSONResponse(w, course)
+}
+
+// Handler to delete a course
+// The id is taken from the path, or from the id query param for older clients
 func (s *Server) deleteCourse(w http.ResponseWriter, r *http.Request) {
-	queryId := r.URL.Query().Get("id")
+	queryId := mux.Vars(r)["id"]
+	if queryId == "" {
+		queryId = r.URL.Query().Get("id")
+	}
 	if queryId == "" {
 		utils.WriteErrorResponse(w, "id is required", http.StatusBadRequest)
 		return
@@ -92,14 +192,17 @@ func (s *Server) deleteCourse(w http.ResponseWriter, r *http.Request) {
 		utils.WriteErrorResponse(w, "Invalid id, id must be a number", http.StatusBadRequest)
 		return
 	}
-	course, err := s.courseStore.GetCourseById(uint(id))
+	course, err := s.courseStore.GetCourseById(r.Context(), uint(id))
 	if err != nil {
-		utils.WriteErrorResponse(w, "course not found", http.StatusNotFound)
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
 		return
 	}
 
-	if err := s.courseStore.DeleteCourse(course); err != nil {
-		s.logger.Error("Failed to delete course", err)
+	if err := s.courseStore.DeleteCourse(r.Context(), course); err != nil {
+		s.log(r).WithError(err).Error("Failed to delete course")
 		utils.WriteErrorResponse(w, "failed to delete course", http.StatusInternalServerError)
 		return
 	}
@@ -5,14 +5,35 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	"github.com/gorilla/mux"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/auth"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/config"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/db"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/generator"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/metrics"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/telemetry"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils/logger"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/version"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gorm.io/gorm"
 )
 
@@ -24,7 +45,7 @@ type MockCourseStore struct {
 	Err     error
 }
 
-func (m *MockCourseStore) ListCourses(limit, offset int) ([]schema.Course, error) {
+func (m *MockCourseStore) ListCourses(ctx context.Context, limit, offset int) ([]schema.Course, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -38,7 +59,7 @@ func (m *MockCourseStore) ListCourses(limit, offset int) ([]schema.Course, error
 	return m.Courses[offset:end], nil
 }
 
-func (m *MockCourseStore) CreateCourse(course *schema.Course) error {
+func (m *MockCourseStore) CreateCourse(ctx context.Context, course *schema.Course) error {
 	if m.Err != nil {
 		return m.Err
 	}
@@ -48,7 +69,7 @@ func (m *MockCourseStore) CreateCourse(course *schema.Course) error {
 	return nil
 }
 
-func (m *MockCourseStore) GetCourseById(id uint) (*schema.Course, error) {
+func (m *MockCourseStore) GetCourseById(ctx context.Context, id uint) (*schema.Course, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -57,10 +78,10 @@ func (m *MockCourseStore) GetCourseById(id uint) (*schema.Course, error) {
 			return &c, nil
 		}
 	}
-	return nil, gorm.ErrRecordNotFound
+	return nil, store.ErrNotFound
 }
 
-func (m *MockCourseStore) DeleteCourse(course *schema.Course) error {
+func (m *MockCourseStore) DeleteCourse(ctx context.Context, course *schema.Course) error {
 	if m.Err != nil {
 		return m.Err
 	}
@@ -70,12 +91,20 @@ func (m *MockCourseStore) DeleteCourse(course *schema.Course) error {
 			return nil
 		}
 	}
-	return gorm.ErrRecordNotFound
+	return store.ErrNotFound
 }
 
-// dummy implementation
-func (m *MockCourseStore) UpdateCourse(course *schema.Course) error {
-	return nil
+func (m *MockCourseStore) UpdateCourse(ctx context.Context, course *schema.Course) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	for i, c := range m.Courses {
+		if c.ID == course.ID {
+			m.Courses[i] = *course
+			return nil
+		}
+	}
+	return store.ErrNotFound
 }
 
 // MockUserStore simulates the behavior of the UserStore
@@ -84,7 +113,7 @@ type MockUserStore struct {
 	Err  error
 }
 
-func (m *MockUserStore) GetUserByUID(uid string) (*schema.User, error) {
+func (m *MockUserStore) GetUserByUID(ctx context.Context, uid string) (*schema.User, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -94,30 +123,404 @@ func (m *MockUserStore) GetUserByUID(uid string) (*schema.User, error) {
 	return nil, errors.New("user not found")
 }
 
+func (m *MockUserStore) GetUserById(ctx context.Context, id uint) (*schema.User, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if m.User.ID == id {
+		return &m.User, nil
+	}
+	return nil, errors.New("user not found")
+}
+
 // Unused in the tests, but required to implement the interface
 func (m *MockUserStore) GetUserFromContext(ctx context.Context) (*schema.User, error) {
 	return &m.User, nil
 }
-func (m *MockUserStore) CreateUser(user *schema.User) error {
+func (m *MockUserStore) CreateUser(ctx context.Context, user *schema.User) error {
+	return nil
+}
+
+// MockQuizStore simulates the behavior of the QuizStore
+type MockQuizStore struct {
+	Quizzes       []schema.Quiz
+	Attempts      []schema.QuizAttempt
+	ItemResponses []store.ItemResponse
+	Bank          *MockQuestionStore // new questions of the quizzes are added to it
+	Err           error
+}
+
+func (m *MockQuizStore) CreateQuiz(ctx context.Context, quiz *schema.Quiz) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	for i := range quiz.Questions {
+		qq := &quiz.Questions[i]
+		if qq.Question.ID == 0 {
+			j := slices.IndexFunc(m.Bank.Questions, func(q schema.Question) bool {
+				return q.CourseID == qq.Question.CourseID && q.Question == qq.Question.Question && slices.Equal(q.Options, qq.Question.Options)
+			})
+			if j < 0 {
+				m.Bank.CreateQuestion(ctx, &qq.Question)
+			} else {
+				qq.Question = m.Bank.Questions[j]
+			}
+		}
+		qq.QuestionID = qq.Question.ID
+		if len(qq.Options) > 0 {
+			qq.Question.Options = qq.Options
+		}
+	}
+	quiz.ID = uint(len(m.Quizzes) + 1)
+	m.Quizzes = append(m.Quizzes, *quiz)
+	return nil
+}
+
+func (m *MockQuizStore) GetQuizById(ctx context.Context, id uint) (*schema.Quiz, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	for _, q := range m.Quizzes {
+		if q.ID == id {
+			return &q, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockQuizStore) ListLessonQuizIDs(ctx context.Context, lessonID uint) ([]uint, error) {
+	ids := []uint{}
+	for _, q := range m.Quizzes {
+		if q.LessonID != nil && *q.LessonID == lessonID {
+			ids = append(ids, q.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MockQuizStore) ListCourseQuizzes(ctx context.Context, courseID uint) ([]schema.Quiz, error) {
+	var quizzes []schema.Quiz
+	for _, q := range m.Quizzes {
+		if q.CourseID == courseID {
+			quizzes = append(quizzes, q)
+		}
+	}
+	return quizzes, nil
+}
+
+func (m *MockQuizStore) UpdateQuizSettings(ctx context.Context, quiz *schema.Quiz) error {
+	for i, q := range m.Quizzes {
+		if q.ID == quiz.ID {
+			m.Quizzes[i].Settings = quiz.Settings
+		}
+	}
+	return nil
+}
+
+func (m *MockQuizStore) UpdateQuizPoints(ctx context.Context, quiz *schema.Quiz) error {
+	for i, q := range m.Quizzes {
+		if q.ID == quiz.ID {
+			m.Quizzes[i].Questions = quiz.Questions
+		}
+	}
+	return nil
+}
+
+func (m *MockQuizStore) StartAttempt(ctx context.Context, attempt *schema.QuizAttempt) error {
+	attempt.ID = uint(len(m.Attempts) + 1)
+	attempt.StartedAt = time.Now()
+	m.Attempts = append(m.Attempts, *attempt)
+	return nil
+}
+
+func (m *MockQuizStore) GetOpenAttempt(ctx context.Context, userID, quizID uint) (*schema.QuizAttempt, error) {
+	for _, a := range m.Attempts {
+		if a.UserID == userID && a.QuizID == quizID && a.SubmittedAt == nil {
+			return &a, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockQuizStore) UpdateAttempt(ctx context.Context, attempt *schema.QuizAttempt) error {
+	for i, a := range m.Attempts {
+		if a.ID == attempt.ID {
+			m.Attempts[i] = *attempt
+			m.Attempts[i].Answers = slices.Clone(attempt.Answers)
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (m *MockQuizStore) SubmitAttempt(ctx context.Context, attempt *schema.QuizAttempt) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	for i, a := range m.Attempts {
+		if a.ID == attempt.ID {
+			m.Attempts[i] = *attempt
+			return nil
+		}
+	}
+	attempt.ID = uint(len(m.Attempts) + 1)
+	m.Attempts = append(m.Attempts, *attempt)
+	return nil
+}
+
+func (m *MockQuizStore) CountSubmittedAttempts(ctx context.Context, userID, quizID uint) (int64, error) {
+	var count int64
+	for _, a := range m.Attempts {
+		if a.UserID == userID && a.QuizID == quizID && a.SubmittedAt != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockQuizStore) ListAttempts(ctx context.Context, filter store.AttemptFilter, limit, offset int) ([]schema.QuizAttempt, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var attempts []schema.QuizAttempt
+	for _, a := range m.Attempts {
+		if a.SubmittedAt == nil || (filter.UserID != 0 && a.UserID != filter.UserID) || (filter.QuizID != 0 && a.QuizID != filter.QuizID) {
+			continue
+		}
+		if filter.CourseID != 0 && !slices.ContainsFunc(m.Quizzes, func(q schema.Quiz) bool { return q.ID == a.QuizID && q.CourseID == filter.CourseID }) {
+			continue
+		}
+		attempts = append(attempts, a)
+	}
+	if offset > len(attempts) {
+		return []schema.QuizAttempt{}, nil
+	}
+	if limit < 0 {
+		return attempts[offset:], nil
+	}
+	return attempts[offset:min(offset+limit, len(attempts))], nil
+}
+
+func (m *MockQuizStore) ListItemResponses(ctx context.Context, courseID uint) ([]store.ItemResponse, error) {
+	return m.ItemResponses, nil
+}
+
+// MockQuestionStore simulates the behavior of the QuestionStore
+type MockQuestionStore struct {
+	Questions []schema.Question
+	Err       error
+}
+
+func (m *MockQuestionStore) CreateQuestion(ctx context.Context, question *schema.Question) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	question.ID = uint(len(m.Questions) + 1)
+	m.Questions = append(m.Questions, *question)
+	return nil
+}
+
+func (m *MockQuestionStore) CreateQuestions(ctx context.Context, questions []schema.Question) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	for i := range questions {
+		questions[i].ID = uint(len(m.Questions) + 1)
+		m.Questions = append(m.Questions, questions[i])
+	}
+	return nil
+}
+
+func (m *MockQuestionStore) GetQuestionById(ctx context.Context, id uint) (*schema.Question, error) {
+	for _, q := range m.Questions {
+		if q.ID == id {
+			return &q, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockQuestionStore) ListQuestions(ctx context.Context, filter store.QuestionFilter, limit, offset int) ([]schema.Question, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var questions []schema.Question
+	for _, q := range m.Questions {
+		if (filter.CourseID != 0 && q.CourseID != filter.CourseID) || (filter.Difficulty != "" && q.Difficulty != filter.Difficulty) || (filter.Type != "" && q.Type != filter.Type) {
+			continue
+		}
+		if filter.Tag != "" && !slices.ContainsFunc(q.Tags, func(t schema.QuestionTag) bool { return t.Name == filter.Tag }) {
+			continue
+		}
+		questions = append(questions, q)
+	}
+	if offset > len(questions) {
+		return []schema.Question{}, nil
+	}
+	if limit < 0 {
+		return questions[offset:], nil
+	}
+	return questions[offset:min(offset+limit, len(questions))], nil
+}
+
+func (m *MockQuestionStore) UpdateQuestion(ctx context.Context, question *schema.Question) error {
+	return m.Err
+}
+
+// dummy implementation
+func (m *MockQuestionStore) DeleteQuestion(ctx context.Context, question *schema.Question) error {
+	return nil
+}
+
+// MockEnrollmentStore simulates the behavior of the EnrollmentStore
+type MockEnrollmentStore struct {
+	Enrollments []schema.Enrollment
+}
+
+func (m *MockEnrollmentStore) GetEnrollment(ctx context.Context, userID, courseID uint) (*schema.Enrollment, error) {
+	for _, e := range m.Enrollments {
+		if e.UserID == userID && e.CourseID == courseID {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockEnrollmentStore) SaveEnrollment(ctx context.Context, enrollment *schema.Enrollment) error {
+	for i, e := range m.Enrollments {
+		if e.ID == enrollment.ID {
+			m.Enrollments[i] = *enrollment
+			return nil
+		}
+	}
+	enrollment.ID = uint(len(m.Enrollments) + 1)
+	m.Enrollments = append(m.Enrollments, *enrollment)
+	return nil
+}
+
+func (m *MockEnrollmentStore) ListEnrollments(ctx context.Context, filter store.EnrollmentFilter, limit, offset int) ([]schema.Enrollment, error) {
+	var enrollments []schema.Enrollment
+	for _, e := range m.Enrollments {
+		if (filter.UserID == 0 || e.UserID == filter.UserID) && (filter.CourseID == 0 || e.CourseID == filter.CourseID) && (filter.Status == "" || e.Status == filter.Status) {
+			enrollments = append(enrollments, e)
+		}
+	}
+	return enrollments, nil
+}
+
+// MockModuleStore simulates the behavior of the ModuleStore
+type MockModuleStore struct {
+	Modules []schema.Module
+	Lessons []schema.Lesson
+}
+
+func (m *MockModuleStore) ListModules(ctx context.Context, courseID uint) ([]schema.Module, error) {
+	var modules []schema.Module
+	for _, mod := range m.Modules {
+		if mod.CourseID == courseID {
+			for _, l := range m.Lessons {
+				if l.ModuleID == mod.ID {
+					mod.Lessons = append(mod.Lessons, l)
+				}
+			}
+			modules = append(modules, mod)
+		}
+	}
+	return modules, nil
+}
+
+func (m *MockModuleStore) GetModuleById(ctx context.Context, id uint) (*schema.Module, error) {
+	for _, mod := range m.Modules {
+		if mod.ID == id {
+			return &mod, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockModuleStore) CreateModule(ctx context.Context, module *schema.Module) error {
+	module.ID = uint(len(m.Modules) + 1)
+	m.Modules = append(m.Modules, *module)
+	return nil
+}
+
+func (m *MockModuleStore) UpdateModule(ctx context.Context, module *schema.Module) error {
+	return nil
+}
+
+func (m *MockModuleStore) DeleteModule(ctx context.Context, module *schema.Module) error {
+	return nil
+}
+
+func (m *MockModuleStore) ReorderModules(ctx context.Context, courseID uint, ids []uint) error {
+	modules, _ := m.ListModules(ctx, courseID)
+	if len(ids) != len(modules) {
+		return store.ErrInvalidOrder
+	}
+	return nil
+}
+
+func (m *MockModuleStore) GetLessonById(ctx context.Context, id uint) (*schema.Lesson, error) {
+	for _, l := range m.Lessons {
+		if l.ID == id {
+			module, err := m.GetModuleById(ctx, l.ModuleID)
+			if err != nil {
+				return nil, err
+			}
+			l.Module = *module
+			return &l, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockModuleStore) CreateLesson(ctx context.Context, lesson *schema.Lesson) error {
+	lesson.ID = uint(len(m.Lessons) + 1)
+	m.Lessons = append(m.Lessons, *lesson)
+	return nil
+}
+
+func (m *MockModuleStore) UpdateLesson(ctx context.Context, lesson *schema.Lesson) error {
+	return nil
+}
+
+func (m *MockModuleStore) DeleteLesson(ctx context.Context, lesson *schema.Lesson) error {
+	return nil
+}
+
+func (m *MockModuleStore) ReorderLessons(ctx context.Context, moduleID uint, ids []uint) error {
 	return nil
 }
 
+// quizQuestions places the questions in a quiz in order, one point each
+func quizQuestions(questions ...schema.Question) []schema.QuizQuestion {
+	placed := make([]schema.QuizQuestion, len(questions))
+	for i, q := range questions {
+		placed[i] = schema.QuizQuestion{QuizID: 1, Position: i, Question: q, QuestionID: q.ID, Points: 1}
+	}
+	return placed
+}
+
 // TestServer setup
 
 // TestServer embeds Server and includes the mocks
 type TestServer struct {
 	*Server
-	mockCourseStore *MockCourseStore
-	mockUserStore   *MockUserStore
+	mockCourseStore     *MockCourseStore
+	mockUserStore       *MockUserStore
+	mockQuizStore       *MockQuizStore
+	mockQuestionStore   *MockQuestionStore
+	mockEnrollmentStore *MockEnrollmentStore
+	mockModuleStore     *MockModuleStore
 }
 
 func newTestServer() *TestServer {
 	logger := logrus.New()
 	mockCourseStore := &MockCourseStore{
 		Courses: []schema.Course{
-			{ID: 1, Title: "Course 1", CreatedAt: time.Now()},
-			{ID: 2, Title: "Course 2", CreatedAt: time.Now()},
-			{ID: 3, Title: "Course 3", CreatedAt: time.Now()},
+			{ID: 1, Title: "Course 1", UserID: 1, CreatedAt: time.Now()},
+			{ID: 2, Title: "Course 2", UserID: 1, CreatedAt: time.Now()},
+			{ID: 3, Title: "Course 3", UserID: 2, CreatedAt: time.Now()},
 		},
 	}
 	mockUserStore := &MockUserStore{
@@ -129,15 +532,61 @@ func newTestServer() *TestServer {
 			Role:  schema.Student,
 		},
 	}
+	mockQuizStore := &MockQuizStore{
+		Quizzes: []schema.Quiz{
+			{ID: 1, CourseID: 1, Questions: quizQuestions(
+				schema.Question{ID: 101, CourseID: 1, Question: "What is the capital of France?", Options: []string{"Berlin", "Madrid", "Paris", "Rome"}, Answer: "Paris"},
+				schema.Question{ID: 102, CourseID: 1, Question: "How many continents are there?", Options: []string{"5", "6", "7", "8"}, Answer: "7"},
+			)},
+		},
+	}
+	mockQuestionStore := &MockQuestionStore{
+		Questions: []schema.Question{
+			{ID: 1, CourseID: 1, Question: "Which planet is known as the Red Planet?", Options: []string{"Earth", "Mars", "Jupiter", "Venus"}, Answer: "Mars", Difficulty: schema.Easy, Tags: []schema.QuestionTag{{Name: "space"}}},
+			{ID: 2, CourseID: 1, Question: "What is the largest ocean on Earth?", Options: []string{"Atlantic", "Indian", "Arctic", "Pacific"}, Answer: "Pacific", Difficulty: schema.Medium},
+			{ID: 3, CourseID: 1, Question: "What is the fastest land animal?", Options: []string{"Cheetah", "Lion", "Horse", "Kangaroo"}, Answer: "Cheetah", Difficulty: schema.Easy},
+		},
+	}
+	mockQuizStore.Bank = mockQuestionStore
+	mockEnrollmentStore := &MockEnrollmentStore{
+		Enrollments: []schema.Enrollment{
+			{ID: 1, UserID: 1, CourseID: 1, Status: schema.EnrollmentActive},
+		},
+	}
+	mockModuleStore := &MockModuleStore{
+		Modules: []schema.Module{
+			{ID: 1, CourseID: 1, Title: "Module 1"},
+			{ID: 2, CourseID: 1, Title: "Module 2", Position: 1},
+			{ID: 3, CourseID: 3, Title: "Module 3"},
+		},
+		Lessons: []schema.Lesson{
+			{ID: 1, ModuleID: 1, Title: "Lesson 1", Body: "# Lesson 1"},
+			{ID: 2, ModuleID: 3, Title: "Lesson 2"},
+		},
+	}
 	s := &Server{
-		courseStore: mockCourseStore,
-		userStore:   mockUserStore,
-		logger:      logger,
+		courseStore:     mockCourseStore,
+		userStore:       mockUserStore,
+		quizStore:       mockQuizStore,
+		questionStore:   mockQuestionStore,
+		enrollmentStore: mockEnrollmentStore,
+		moduleStore:     mockModuleStore,
+		logger:          logger,
+		metrics:         metrics.New(),
+		generators: map[string]generator.QuizGenerator{
+			generator.Bank:     generator.NewBank(mockQuestionStore),
+			generator.Template: generator.NewTemplate(),
+		},
+		quizGenerator: generator.Bank,
 	}
 	return &TestServer{
-		Server:          s,
-		mockCourseStore: mockCourseStore,
-		mockUserStore:   mockUserStore,
+		Server:              s,
+		mockCourseStore:     mockCourseStore,
+		mockUserStore:       mockUserStore,
+		mockQuizStore:       mockQuizStore,
+		mockQuestionStore:   mockQuestionStore,
+		mockEnrollmentStore: mockEnrollmentStore,
+		mockModuleStore:     mockModuleStore,
 	}
 }
 
@@ -230,3 +679,1850 @@ func TestCreateCourse_InvalidContentType(t *testing.T) {
 		t.Errorf("expected status 400 Bad Request, got %d", res.StatusCode)
 	}
 }
+
+// Tests for submitQuiz
+func TestSubmitQuiz_Success(t *testing.T) {
+	ts := newTestServer()
+
+	body, _ := json.Marshal(map[string]any{"quiz_id": 1, "answers": []string{"paris", "6"}})
+	req := httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, req)
+	res := rr.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", res.StatusCode)
+	}
+	var attempt schema.QuizAttempt
+	if err := json.NewDecoder(res.Body).Decode(&attempt); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if attempt.Score != 1 || attempt.MaxScore != 2 {
+		t.Errorf("expected score 1/2, got %v/%v", attempt.Score, attempt.MaxScore)
+	}
+	if len(attempt.Answers) != 2 || !attempt.Answers[0].Correct || attempt.Answers[1].Correct {
+		t.Errorf("unexpected per question results %+v", attempt.Answers)
+	}
+	if attempt.SubmittedAt == nil {
+		t.Errorf("expected submitted_at to be set")
+	}
+	if len(ts.mockQuizStore.Attempts) != 1 {
+		t.Errorf("expected 1 stored attempt, got %d", len(ts.mockQuizStore.Attempts))
+	}
+}
+
+func TestSubmitQuiz_TooManyAnswers(t *testing.T) {
+	ts := newTestServer()
+
+	body, _ := json.Marshal(map[string]any{"quiz_id": 1, "answers": []string{"Paris", "7", "Mars"}})
+	req := httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+}
+
+// Tests for quiz history
+func TestGetQuizHistory_OnlyOwnSubmittedAttempts(t *testing.T) {
+	ts := newTestServer()
+	submitted := time.Now()
+	ts.mockQuizStore.Attempts = []schema.QuizAttempt{
+		{ID: 1, UserID: 1, QuizID: 1, Score: 2, MaxScore: 2, SubmittedAt: &submitted},
+		{ID: 2, UserID: 1, QuizID: 1},
+		{ID: 3, UserID: 2, QuizID: 1, Score: 1, MaxScore: 2, SubmittedAt: &submitted},
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/quiz/history", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	rr := httptest.NewRecorder()
+	ts.getQuizHistory(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rr.Code)
+	}
+	var history []attemptSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &history); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(history) != 1 || history[0].ID != 1 {
+		t.Errorf("expected only attempt 1, got %+v", history)
+	}
+}
+
+func TestGetQuizAttempts_OnlyOwner(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.ID, ts.mockUserStore.User.Role = 2, schema.Educator
+
+	for query, status := range map[string]int{"course_id=1": http.StatusForbidden, "quiz_id=1": http.StatusForbidden, "course_id=3": http.StatusOK, "quiz_id=9": http.StatusNotFound} {
+		req := httptest.NewRequest("GET", "/api/v1/quiz/attempts?"+query, nil)
+		rr := httptest.NewRecorder()
+		ts.getQuizAttempts(rr, req)
+		if rr.Code != status {
+			t.Errorf("expected status %d for %s, got %d", status, query, rr.Code)
+		}
+	}
+}
+
+func TestGetQuizAttempts_RequiresFilter(t *testing.T) {
+	ts := newTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/quiz/attempts", nil)
+	rr := httptest.NewRecorder()
+	ts.getQuizAttempts(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+}
+
+// Tests for getQuiz
+func TestGetQuiz_EducatorOfAnotherCourse(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.ID, ts.mockUserStore.User.Role = 2, schema.Educator
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/quiz?course_id=1&quiz_id=1", nil)
+	ctx := context.WithValue(req.Context(), "userID", "test-uid")
+	ctx = context.WithValue(ctx, "userRole", schema.Educator)
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGetQuiz_StudentDoesNotSeeAnswers(t *testing.T) {
+	ts := newTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/quiz?course_id=1&quiz_id=1&shuffle_options=true", nil)
+	ctx := context.WithValue(req.Context(), "userID", "test-uid")
+	ctx = context.WithValue(ctx, "userRole", schema.Student)
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rr.Code)
+	}
+	var quiz schema.Quiz
+	if err := json.Unmarshal(rr.Body.Bytes(), &quiz); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(quiz.Questions) != 2 {
+		t.Fatalf("expected 2 questions, got %d", len(quiz.Questions))
+	}
+	for _, qq := range quiz.Questions {
+		q := qq.Question
+		if q.Answer != "" {
+			t.Errorf("expected answer to be hidden, got %q", q.Answer)
+		}
+		if len(q.Options) != 4 {
+			t.Errorf("expected 4 options, got %d", len(q.Options))
+		}
+	}
+	if len(ts.mockQuizStore.Attempts) != 1 {
+		t.Errorf("expected an attempt to be started")
+	}
+}
+
+func TestGetQuiz_EducatorSeesAnswers(t *testing.T) {
+	ts := newTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/quiz?course_id=1&quiz_id=1", nil)
+	ctx := context.WithValue(req.Context(), "userID", "test-uid")
+	ctx = context.WithValue(ctx, "userRole", schema.Educator)
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req.WithContext(ctx))
+
+	var quiz schema.Quiz
+	if err := json.Unmarshal(rr.Body.Bytes(), &quiz); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(quiz.Questions) != 2 || quiz.Questions[0].Question.Answer != "Paris" || quiz.Questions[1].Points != 1 {
+		t.Errorf("expected the full quiz, got %+v", quiz.Questions)
+	}
+}
+
+// Tests for generateQuiz
+func TestGenerateQuiz_FromQuestionBank(t *testing.T) {
+	ts := newTestServer()
+
+	body, _ := json.Marshal(map[string]string{"course_id": "1", "number": "2", "difficulty": "easy"})
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Educator))
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var quiz schema.Quiz
+	if err := json.Unmarshal(rr.Body.Bytes(), &quiz); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(quiz.Questions) != 2 {
+		t.Fatalf("expected 2 questions, got %d", len(quiz.Questions))
+	}
+	for i, qq := range quiz.Questions {
+		if qq.Position != i || qq.Question.ID == 0 {
+			t.Errorf("expected question %d to reference the bank, got %+v", i, qq)
+		}
+		if q := qq.Question; q.Difficulty != schema.Easy {
+			t.Errorf("expected only EASY questions, got %s", q.Difficulty)
+		}
+	}
+}
+
+func TestGenerateQuiz_NotEnoughQuestions(t *testing.T) {
+	ts := newTestServer()
+
+	body, _ := json.Marshal(map[string]string{"course_id": "1", "number": "2", "tag": "space"})
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+}
+
+// Tests for postQuestion
+func TestPostQuestion_AnswerMustBeAnOption(t *testing.T) {
+	ts := newTestServer()
+
+	body, _ := json.Marshal(map[string]any{"question": "2 + 2?", "options": []string{"3", "5"}, "answer": "4"})
+	req := httptest.NewRequest("POST", "/api/v1/courses/1/questions", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+	ts.postQuestion(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+	if len(ts.mockQuestionStore.Questions) != 3 {
+		t.Errorf("expected no question to be created")
+	}
+}
+
+func TestPostQuestion_Success(t *testing.T) {
+	ts := newTestServer()
+
+	body, _ := json.Marshal(map[string]any{"question": "2 + 2?", "options": []string{"3", "4"}, "answer": "4", "tags": []string{" Math "}})
+	req := httptest.NewRequest("POST", "/api/v1/courses/1/questions", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+	ts.postQuestion(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var question schema.Question
+	if err := json.Unmarshal(rr.Body.Bytes(), &question); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if question.CourseID != 1 || question.Difficulty != schema.Medium {
+		t.Errorf("unexpected question %+v", question)
+	}
+	if len(question.Tags) != 1 || question.Tags[0].Name != "math" {
+		t.Errorf("expected tags to be normalized, got %+v", question.Tags)
+	}
+}
+
+func TestGetQuestions_OnlyOwner(t *testing.T) {
+	ts := newTestServer()
+
+	for id, status := range map[string]int{"1": http.StatusOK, "3": http.StatusForbidden, "9": http.StatusNotFound} {
+		req := httptest.NewRequest("GET", "/api/v1/courses/"+id+"/questions", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		ts.getQuestions(rr, req)
+		if rr.Code != status {
+			t.Errorf("expected status %d for course %s, got %d", status, id, rr.Code)
+		}
+	}
+}
+
+func TestPutQuestion_InUse(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuestionStore.Err = store.ErrQuestionInUse
+
+	body, _ := json.Marshal(map[string]any{"question": "Which planet is red?", "options": []string{"Earth", "Mars"}, "answer": "Mars"})
+	req := httptest.NewRequest("PUT", "/api/v1/questions/1", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+	ts.putQuestion(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGenerateQuiz_OnlyOwner(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.ID, ts.mockUserStore.User.Role = 2, schema.Educator
+
+	body, _ := json.Marshal(map[string]any{"course_id": "1", "number": "2"})
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Educator))
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(ts.mockQuizStore.Quizzes) != 1 {
+		t.Errorf("expected no quiz to be created, got %d quizzes", len(ts.mockQuizStore.Quizzes))
+	}
+}
+
+func TestGenerateQuiz_SameSeedSameQuiz(t *testing.T) {
+	ts := newTestServer()
+
+	generate := func() schema.Quiz {
+		body, _ := json.Marshal(map[string]any{"course_id": "1", "number": "2", "seed": 42})
+		req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Educator))
+		rr := httptest.NewRecorder()
+		ts.generateQuiz(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var quiz schema.Quiz
+		json.Unmarshal(rr.Body.Bytes(), &quiz)
+		return quiz
+	}
+
+	first, second := generate(), generate()
+	if first.Seed != 42 {
+		t.Errorf("expected seed 42 to be recorded, got %d", first.Seed)
+	}
+	ids := func(quiz schema.Quiz) []uint {
+		var ids []uint
+		for _, qq := range quiz.Questions {
+			ids = append(ids, qq.QuestionID)
+		}
+		return ids
+	}
+	if !slices.Equal(ids(first), ids(second)) {
+		t.Errorf("expected the same questions for the same seed, got %v and %v", ids(first), ids(second))
+	}
+	for i, qq := range first.Questions {
+		if !slices.Equal(qq.Question.Options, second.Questions[i].Question.Options) {
+			t.Errorf("expected the same options order for the same seed, got %v and %v", qq.Question.Options, second.Questions[i].Question.Options)
+		}
+	}
+	for _, quiz := range ts.mockQuizStore.Quizzes[len(ts.mockQuizStore.Quizzes)-2:] {
+		for _, qq := range quiz.Questions {
+			if !slices.Equal(qq.Options, qq.Question.Options) {
+				t.Errorf("expected the options order to be stored with the quiz, got %v for %v", qq.Options, qq.Question.Options)
+			}
+		}
+	}
+}
+
+func TestSubmitQuiz_PerStudentVariant(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes[0].PerStudentVariants = true
+	ts.mockQuizStore.Quizzes[0].Seed = 7
+
+	// answer the questions in the order the variant was served
+	req := httptest.NewRequest("GET", "/api/v1/courses/quiz?course_id=1&quiz_id=1", nil)
+	ctx := context.WithValue(req.Context(), "userID", "test-uid")
+	ctx = context.WithValue(ctx, "userRole", schema.Student)
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req.WithContext(ctx))
+
+	var served schema.Quiz
+	json.Unmarshal(rr.Body.Bytes(), &served)
+	correct := map[string]string{"What is the capital of France?": "Paris", "How many continents are there?": "7"}
+	var answers []string
+	for _, qq := range served.Questions {
+		answers = append(answers, correct[qq.Question.Question])
+	}
+
+	body, _ := json.Marshal(map[string]any{"quiz_id": 1, "answers": answers})
+	req = httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	ts.submitQuiz(rr, req.WithContext(ctx))
+
+	var attempt schema.QuizAttempt
+	if err := json.Unmarshal(rr.Body.Bytes(), &attempt); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if attempt.Score != 2 {
+		t.Errorf("expected full score on the variant, got %v", attempt.Score)
+	}
+	if attempt.VariantSeed != variantSeed(7, 1) {
+		t.Errorf("expected the variant seed to be recorded, got %d", attempt.VariantSeed)
+	}
+}
+
+// Tests for updateCourse and deleteCourse
+func newCourseRequest(method, id string, body any) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, "/api/v1/courses/"+id, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	return mux.SetURLVars(req, map[string]string{"id": id})
+}
+
+func TestUpdateCourse_Owner(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.updateCourse(rr, newCourseRequest("PATCH", "1", map[string]string{"title": "Renamed"}))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ts.mockCourseStore.Courses[0].Title != "Renamed" {
+		t.Errorf("expected the course to be renamed, got %q", ts.mockCourseStore.Courses[0].Title)
+	}
+}
+
+func TestUpdateCourse_PutRequiresTitle(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.updateCourse(rr, newCourseRequest("PUT", "1", map[string]string{}))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+}
+
+func TestUpdateCourse_NotOwner(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.updateCourse(rr, newCourseRequest("PUT", "3", map[string]string{"title": "Renamed"}))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+	if ts.mockCourseStore.Courses[2].Title != "Course 3" {
+		t.Errorf("expected the course to be unchanged")
+	}
+}
+
+func TestDeleteCourse_NotOwner(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.deleteCourse(rr, newCourseRequest("DELETE", "3", nil))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+	if len(ts.mockCourseStore.Courses) != 3 {
+		t.Errorf("expected the course not to be deleted")
+	}
+}
+
+func TestDeleteCourse_AdminCanDeleteAnyCourse(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.Role = schema.Admin
+
+	rr := httptest.NewRecorder()
+	ts.deleteCourse(rr, newCourseRequest("DELETE", "3", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 OK, got %d", rr.Code)
+	}
+	if len(ts.mockCourseStore.Courses) != 2 {
+		t.Errorf("expected the course to be deleted")
+	}
+}
+
+// Tests for enrollments
+func TestGetQuiz_StudentNotEnrolled(t *testing.T) {
+	ts := newTestServer()
+	ts.mockEnrollmentStore.Enrollments[0].Status = schema.EnrollmentDropped
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/quiz?course_id=1&quiz_id=1", nil)
+	ctx := context.WithValue(req.Context(), "userID", "test-uid")
+	ctx = context.WithValue(ctx, "userRole", schema.Student)
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+	if len(ts.mockQuizStore.Attempts) != 0 {
+		t.Errorf("expected no attempt to be started")
+	}
+}
+
+func TestEnrollCourse_ReactivatesDroppedEnrollment(t *testing.T) {
+	ts := newTestServer()
+	ts.mockEnrollmentStore.Enrollments[0].Status = schema.EnrollmentDropped
+
+	rr := httptest.NewRecorder()
+	ts.enrollCourse(rr, newCourseRequest("POST", "1", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(ts.mockEnrollmentStore.Enrollments) != 1 || ts.mockEnrollmentStore.Enrollments[0].Status != schema.EnrollmentActive {
+		t.Errorf("expected the enrollment to be active again, got %+v", ts.mockEnrollmentStore.Enrollments)
+	}
+}
+
+func TestEnrollCourse_AlreadyEnrolled(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.enrollCourse(rr, newCourseRequest("POST", "1", nil))
+
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict, got %d", rr.Code)
+	}
+}
+
+func TestGetRoster_NotOwner(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.Role = schema.Educator
+
+	rr := httptest.NewRecorder()
+	ts.getRoster(rr, newCourseRequest("GET", "3", nil))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+}
+
+// Tests for modules and lessons
+func TestGetModules_StudentNotEnrolled(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.getModules(rr, newCourseRequest("GET", "3", nil))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+}
+
+func TestPostModule_Owner(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.Role = schema.Educator
+
+	rr := httptest.NewRecorder()
+	ts.postModule(rr, newCourseRequest("POST", "1", map[string]string{"title": "Module 4"}))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(ts.mockModuleStore.Modules) != 4 || ts.mockModuleStore.Modules[3].CourseID != 1 {
+		t.Errorf("expected the module to be added to course 1, got %+v", ts.mockModuleStore.Modules)
+	}
+}
+
+func TestPostLesson_NotOwner(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.Role = schema.Educator
+
+	rr := httptest.NewRecorder()
+	ts.postLesson(rr, newCourseRequest("POST", "3", map[string]any{"title": "Lesson", "body": "text", "estimated_minutes": 10}))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+	if len(ts.mockModuleStore.Lessons) != 2 {
+		t.Errorf("expected no lesson to be added")
+	}
+}
+
+func TestReorderModules_MustListEveryModule(t *testing.T) {
+	ts := newTestServer()
+	ts.mockUserStore.User.Role = schema.Educator
+
+	rr := httptest.NewRecorder()
+	ts.reorderModules(rr, newCourseRequest("PUT", "1", map[string][]uint{"ids": {2}}))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+}
+
+func TestModuleStore_NewPositionsAfterDelete(t *testing.T) {
+	t.Chdir(t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Create(&schema.User{ID: 1, UID: "test-uid", Email: "test@example.com", Role: schema.Educator})
+	database.Create(&schema.Course{ID: 1, Title: "Course 1", UserID: 1})
+	modules := store.NewModuleStore(store.NewStore(database, logrus.New()))
+	ctx := context.Background()
+
+	created := make([]schema.Module, 3)
+	for i := range created {
+		created[i] = schema.Module{CourseID: 1, Title: fmt.Sprintf("Module %d", i)}
+		if err := modules.CreateModule(ctx, &created[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := modules.DeleteModule(ctx, &created[0]); err != nil {
+		t.Fatal(err)
+	}
+	module := schema.Module{CourseID: 1, Title: "Module 3"}
+	if err := modules.CreateModule(ctx, &module); err != nil {
+		t.Fatal(err)
+	}
+	if module.Position != 3 {
+		t.Errorf("expected the new module after the last one at position 3, got %d", module.Position)
+	}
+
+	lessons := make([]schema.Lesson, 2)
+	for i := range lessons {
+		lessons[i] = schema.Lesson{ModuleID: module.ID, Title: fmt.Sprintf("Lesson %d", i)}
+		if err := modules.CreateLesson(ctx, &lessons[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := modules.DeleteLesson(ctx, &lessons[0]); err != nil {
+		t.Fatal(err)
+	}
+	lesson := schema.Lesson{ModuleID: module.ID, Title: "Lesson 2"}
+	if err := modules.CreateLesson(ctx, &lesson); err != nil {
+		t.Fatal(err)
+	}
+	if lesson.Position != 2 {
+		t.Errorf("expected the new lesson after the last one at position 2, got %d", lesson.Position)
+	}
+}
+
+func TestGenerateQuiz_AttachToLesson(t *testing.T) {
+	ts := newTestServer()
+
+	body := `{"course_id":"1","number":"2","lesson_id":1}`
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	created := ts.mockQuizStore.Quizzes[len(ts.mockQuizStore.Quizzes)-1]
+	if created.LessonID == nil || *created.LessonID != 1 {
+		t.Errorf("expected the quiz to be attached to lesson 1, got %v", created.LessonID)
+	}
+
+	rr = httptest.NewRecorder()
+	ts.getLesson(rr, newCourseRequest("GET", "1", nil))
+	var lesson struct {
+		QuizIDs []uint `json:"quiz_ids"`
+	}
+	json.NewDecoder(rr.Body).Decode(&lesson)
+	if len(lesson.QuizIDs) != 1 || lesson.QuizIDs[0] != created.ID {
+		t.Errorf("expected the lesson to list quiz %d, got %v", created.ID, lesson.QuizIDs)
+	}
+}
+
+func TestGenerateQuiz_LessonOfAnotherCourse(t *testing.T) {
+	ts := newTestServer()
+
+	body := `{"course_id":"1","number":"2","lesson_id":2}`
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 Not Found, got %d", rr.Code)
+	}
+}
+
+func TestNewGenerators_LLMTimeoutUnderWriteTimeout(t *testing.T) {
+	saved := config.Envs
+	t.Cleanup(func() { config.Envs = saved })
+	config.Envs.LLMURL = "http://localhost:11434/v1"
+
+	for timeout, valid := range map[int]bool{10: true, 15: false, 60: false, 0: false} {
+		config.Envs.LLMTimeout = timeout
+		generators, err := newGenerators(&MockQuestionStore{})
+		if valid && (err != nil || generators[generator.LLM] == nil) {
+			t.Errorf("expected a %ds timeout to be accepted, got %v", timeout, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected a %ds timeout to be rejected", timeout)
+		}
+	}
+}
+
+func TestGenerateQuiz_TemplateGenerator(t *testing.T) {
+	ts := newTestServer()
+	ts.mockModuleStore.Lessons[0].Body = "# Lesson 1\n\n**Mars**: the red planet\n**Pacific**: the largest ocean\n- Cheetah: the fastest land animal"
+
+	body := `{"course_id":"1","number":"3","generator":"template","type":"single_choice","seed":5}`
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Educator))
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	created := ts.mockQuizStore.Quizzes[len(ts.mockQuizStore.Quizzes)-1]
+	if created.Generator != generator.Template || len(created.Questions) != 3 {
+		t.Fatalf("expected a template quiz of 3 questions, got %+v", created)
+	}
+	if len(ts.mockQuestionStore.Questions) != 6 {
+		t.Fatalf("expected the questions to be added to the bank, got %d questions", len(ts.mockQuestionStore.Questions))
+	}
+	for _, qq := range created.Questions {
+		q := qq.Question
+		if qq.QuestionID == 0 || q.CourseID != 1 || q.Type != schema.SingleChoice || len(q.Tags) != 1 || q.Tags[0].Name != "generated" {
+			t.Errorf("expected a generated question of the bank, got %+v", qq)
+		}
+	}
+}
+
+func TestGenerateQuiz_GeneratorErrors(t *testing.T) {
+	ts := newTestServer()
+
+	for body, want := range map[string]int{
+		`{"course_id":"1","number":"1","generator":"llm"}`:                    http.StatusBadRequest, // not configured
+		`{"course_id":"1","number":"1","generator":"template","tag":"space"}`: http.StatusBadRequest,
+		`{"course_id":"1","number":"1","generator":"template"}`:               http.StatusBadRequest, // the lesson defines nothing
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		ts.generateQuiz(rr, req)
+		if rr.Code != want {
+			t.Errorf("%s: expected status %d, got %d", body, want, rr.Code)
+		}
+	}
+	if len(ts.mockQuestionStore.Questions) != 3 {
+		t.Errorf("expected no question to be added to the bank")
+	}
+}
+
+func TestGenerateQuiz_ReusesBankQuestions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Create(&schema.User{ID: 1, UID: "test-uid", Email: "test@example.com", Role: schema.Educator})
+	database.Create(&schema.Course{ID: 1, Title: "Course 1", UserID: 1})
+	ts := newTestServer()
+	stores := store.NewStore(database, ts.logger)
+	ts.courseStore, ts.quizStore, ts.questionStore = store.NewCourseStore(stores), store.NewQuizStore(stores), store.NewQuestionStore(stores)
+	ts.mockModuleStore.Lessons[0].Body = "# Lesson 1\n\n**Mars**: the red planet\n**Pacific**: the largest ocean\n- Cheetah: the fastest land animal"
+
+	generate := func() schema.Quiz {
+		body := `{"course_id":"1","number":"3","generator":"template","type":"single_choice","seed":5}`
+		req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Educator))
+		rr := httptest.NewRecorder()
+		ts.generateQuiz(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var quiz schema.Quiz
+		json.Unmarshal(rr.Body.Bytes(), &quiz)
+		return quiz
+	}
+	first, second := generate(), generate()
+
+	var count int64
+	database.Model(&schema.Question{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expected the second quiz to reuse the questions of the first, got %d questions in the bank", count)
+	}
+	stored, err := ts.quizStore.GetQuizById(context.Background(), second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, qq := range stored.Questions {
+		if qq.QuestionID != first.Questions[i].QuestionID || !slices.Equal(qq.Question.Options, second.Questions[i].Question.Options) {
+			t.Errorf("expected question %d to be served as generated, got %+v and %+v", i, qq, second.Questions[i])
+		}
+	}
+}
+
+func TestGenerateQuiz_InvalidGeneratedQuestion(t *testing.T) {
+	ts := newTestServer()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content := `{"questions": [{"type": "SINGLE_CHOICE", "question": "Which planet is red?", "options": ["Mars", "Venus"], "answer": "Pluto"}]}`
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": map[string]string{"content": content}}}})
+	}))
+	defer server.Close()
+	ts.generators[generator.LLM] = generator.NewLLM(server.URL, "", "tiny", time.Second)
+
+	body := `{"course_id":"1","number":"1","generator":"llm"}`
+	req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	ts.generateQuiz(rr, req)
+
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502 Bad Gateway, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(ts.mockQuestionStore.Questions) != 3 || len(ts.mockQuizStore.Quizzes) != 1 {
+		t.Errorf("expected nothing to be created")
+	}
+}
+
+// adaptiveQuiz adds an adaptive quiz of 4 questions to the test server, serving 3 of them
+func adaptiveQuiz(ts *TestServer) uint {
+	quiz := schema.Quiz{CourseID: 1, Seed: 9, Adaptive: true, AdaptiveLength: 3, Questions: quizQuestions(
+		schema.Question{ID: 201, Question: "2 + 2?", Options: []string{"3", "4"}, Answer: "4", Difficulty: schema.Easy},
+		schema.Question{ID: 202, Question: "7 * 8?", Options: []string{"54", "56"}, Answer: "56", Difficulty: schema.Medium},
+		schema.Question{ID: 203, Question: "17 * 23?", Options: []string{"391", "401"}, Answer: "391", Difficulty: schema.Hard},
+		schema.Question{ID: 204, Question: "2 ^ 10?", Options: []string{"1024", "2048"}, Answer: "1024", Difficulty: schema.Hard},
+	)}
+	ts.mockQuizStore.CreateQuiz(context.Background(), &quiz)
+	return quiz.ID
+}
+
+func adaptiveRequest(method, quizID, body string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/quiz/"+quizID+"/next", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Student))
+	return mux.SetURLVars(req, map[string]string{"id": quizID})
+}
+
+func TestAdaptiveQuiz_ServesByAbility(t *testing.T) {
+	ts := newTestServer()
+	id := fmt.Sprint(adaptiveQuiz(ts))
+
+	next := func() nextQuestion {
+		rr := httptest.NewRecorder()
+		ts.getNextQuestion(rr, adaptiveRequest("GET", id, ""))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var q nextQuestion
+		json.NewDecoder(rr.Body).Decode(&q)
+		return q
+	}
+	answer := func(value string) schema.QuizAttempt {
+		rr := httptest.NewRecorder()
+		ts.answerQuestion(rr, adaptiveRequest("POST", id, `{"answer":"`+value+`"}`))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var attempt schema.QuizAttempt
+		json.NewDecoder(rr.Body).Decode(&attempt)
+		return attempt
+	}
+
+	// an average student starts with the medium question, which is served again until answered
+	first := next()
+	if first.Question.ID != 202 || first.Number != 1 || first.Total != 3 || next().Question.ID != 202 {
+		t.Fatalf("expected the medium question first, got %+v", first)
+	}
+	attempt := answer("54")
+	if attempt.Ability == nil || *attempt.Ability >= 0 || attempt.SubmittedAt != nil || len(attempt.Answers) != 1 {
+		t.Fatalf("expected a lower ability after a wrong answer, got %+v", attempt)
+	}
+
+	// a wrong answer leads to the easy question, a right one back up to a hard question
+	if second := next(); second.Question.ID != 201 || second.Number != 2 {
+		t.Fatalf("expected the easy question, got %+v", second)
+	}
+	answer("4")
+	third := next()
+	if third.Question.ID != 203 && third.Question.ID != 204 {
+		t.Fatalf("expected a hard question, got %+v", third)
+	}
+	attempt = answer(third.Question.Options[0])
+
+	if attempt.SubmittedAt == nil || attempt.Score != 2 || attempt.MaxScore != 3 || attempt.Ability == nil {
+		t.Errorf("expected the attempt to be submitted with a score of 2/3 and an ability, got %+v", attempt)
+	}
+	rr := httptest.NewRecorder()
+	ts.answerQuestion(rr, adaptiveRequest("POST", id, `{"answer":"4"}`))
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict once the attempt is submitted, got %d", rr.Code)
+	}
+}
+
+func TestAdaptiveQuiz_QuestionsNotListed(t *testing.T) {
+	ts := newTestServer()
+	id := adaptiveQuiz(ts)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/courses/quiz?course_id=1&quiz_id=%d", id), nil)
+	req = req.WithContext(context.WithValue(req.Context(), "userRole", schema.Student))
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req)
+	var quiz studentQuiz
+	json.NewDecoder(rr.Body).Decode(&quiz)
+	if rr.Code != http.StatusOK || len(quiz.Questions) != 0 {
+		t.Errorf("expected the quiz without its questions, got %d: %+v", rr.Code, quiz.Questions)
+	}
+
+	body, _ := json.Marshal(map[string]any{"quiz_id": id, "answers": []string{"4"}})
+	req = httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	ts.submitQuiz(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 Conflict, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ts.getNextQuestion(rr, adaptiveRequest("GET", "1", ""))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for a quiz which is not adaptive, got %d", rr.Code)
+	}
+}
+
+func TestGenerateQuiz_AdaptiveLength(t *testing.T) {
+	ts := newTestServer()
+
+	for body, want := range map[string]int{
+		`{"course_id":"1","number":"3","adaptive":true,"adaptive_length":2}`:         http.StatusOK,
+		`{"course_id":"1","number":"3","adaptive":true,"adaptive_length":4}`:         http.StatusBadRequest,
+		`{"course_id":"1","number":"3","adaptive_length":2}`:                         http.StatusBadRequest,
+		`{"course_id":"1","number":"3","adaptive":true,"per_student_variants":true}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/quiz/generate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		ts.generateQuiz(rr, req)
+		if rr.Code != want {
+			t.Errorf("%s: expected status %d, got %d: %s", body, want, rr.Code, rr.Body.String())
+		}
+	}
+	if created := ts.mockQuizStore.Quizzes[len(ts.mockQuizStore.Quizzes)-1]; !created.Adaptive || created.AdaptiveLength != 2 {
+		t.Errorf("expected an adaptive quiz serving 2 questions, got %+v", created)
+	}
+}
+
+// Tests for error responses
+func TestErrorResponse_Envelope(t *testing.T) {
+	ts := newTestServer()
+
+	req := newCourseRequest("GET", "42", nil)
+	req.Header.Set(utils.RequestIDHeader, "req-123")
+	rr := httptest.NewRecorder()
+	requestIDMiddleware(http.HandlerFunc(ts.getModules)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 Not Found, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON error, got Content-Type %q", ct)
+	}
+	var apiErr utils.APIError
+	if err := json.NewDecoder(rr.Body).Decode(&apiErr); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+	if apiErr.Code != "not_found" || apiErr.Message != "course not found" || apiErr.RequestID != "req-123" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestErrorResponse_DatabaseFailureIsNotNotFound(t *testing.T) {
+	ts := newTestServer()
+	ts.mockCourseStore.Err = errors.New("failed to get course")
+
+	rr := httptest.NewRecorder()
+	ts.updateCourse(rr, newCourseRequest("PATCH", "1", map[string]string{"title": "Renamed"}))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 Internal Server Error, got %d", rr.Code)
+	}
+}
+
+// Tests for question types
+func TestValidateQuestion_InvalidAnswers(t *testing.T) {
+	tests := []schema.Question{
+		{Type: "ESSAY", Question: "q", Answer: "a"},
+		{Type: schema.MultiSelect, Question: "q", Options: []string{"a", "b"}, Answers: []string{"c"}},
+		{Type: schema.TrueFalse, Question: "q", Answer: "maybe"},
+		{Type: schema.Numeric, Question: "q", Answer: "pi"},
+		{Type: schema.ShortText, Question: "q", Pattern: "("},
+		{Type: schema.Ordering, Question: "q", Options: []string{"a", "b"}, Answers: []string{"a", "c"}},
+		{Type: schema.Matching, Question: "q", Options: []string{"a", "b"}, Matches: []string{"x", "y"}, Answers: []string{"x"}},
+	}
+	for _, q := range tests {
+		if err := validateQuestion(&q); err == nil {
+			t.Errorf("expected %s question %+v to be rejected", q.Type, q)
+		}
+	}
+}
+
+func TestSubmitQuiz_ListAndNumericAnswers(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes = append(ts.mockQuizStore.Quizzes, schema.Quiz{
+		ID:       2,
+		CourseID: 1,
+		Questions: quizQuestions(
+			schema.Question{ID: 103, Type: schema.MultiSelect, Question: "Which are primes?", Options: []string{"2", "4", "5"}, Answers: []string{"2", "5"}},
+			schema.Question{ID: 104, Type: schema.Numeric, Question: "What is 7 / 2?", Answer: "3.5"},
+		),
+	})
+
+	body := `{"quiz_id":2,"answers":[["5","2"],3.5]}`
+	req := httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var attempt schema.QuizAttempt
+	json.NewDecoder(rr.Body).Decode(&attempt)
+	if attempt.Score != 2 {
+		t.Errorf("expected score 2/2, got %v/%v", attempt.Score, attempt.MaxScore)
+	}
+	if len(attempt.Answers) != 2 || len(attempt.Answers[0].Answers) != 2 || attempt.Answers[1].Answer != "3.5" {
+		t.Errorf("unexpected recorded answers %+v", attempt.Answers)
+	}
+}
+
+func TestSubmitQuiz_WeightedQuestions(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes[0].Questions[1].Points = 3
+
+	body, _ := json.Marshal(map[string]any{"quiz_id": 1, "answers": []string{"Rome", "7"}})
+	req := httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, req)
+
+	var attempt schema.QuizAttempt
+	if err := json.Unmarshal(rr.Body.Bytes(), &attempt); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if attempt.Score != 3 || attempt.MaxScore != 4 {
+		t.Errorf("expected score 3/4, got %v/%v", attempt.Score, attempt.MaxScore)
+	}
+}
+
+func newSubmitRequest(body any) *http.Request {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/api/v1/quiz/submit", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	return req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+}
+
+func TestGetQuiz_NotOpenYet(t *testing.T) {
+	ts := newTestServer()
+	opens := time.Now().Add(time.Hour)
+	ts.mockQuizStore.Quizzes[0].Settings.OpensAt = &opens
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/quiz?course_id=1&quiz_id=1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	rr := httptest.NewRecorder()
+	ts.getQuiz(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+	if len(ts.mockQuizStore.Attempts) != 0 {
+		t.Errorf("expected no attempt to be started, got %d", len(ts.mockQuizStore.Attempts))
+	}
+}
+
+func TestStartQuiz_RecordsDeadline(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes[0].Settings.TimeLimitMinutes = 30
+
+	var deadlines []*time.Time
+	for range 2 {
+		body, _ := json.Marshal(map[string]any{"quiz_id": 1})
+		req := httptest.NewRequest("POST", "/api/v1/quiz/start", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+		rr := httptest.NewRecorder()
+		ts.startQuiz(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var attempt schema.QuizAttempt
+		json.NewDecoder(rr.Body).Decode(&attempt)
+		deadlines = append(deadlines, attempt.Deadline)
+	}
+
+	if deadlines[0] == nil || time.Until(*deadlines[0]) < 29*time.Minute || time.Until(*deadlines[0]) > 30*time.Minute {
+		t.Fatalf("expected a deadline in 30 minutes, got %v", deadlines[0])
+	}
+	if deadlines[1] == nil || !deadlines[1].Equal(*deadlines[0]) {
+		t.Errorf("expected the attempt in progress to be resumed, got deadline %v", deadlines[1])
+	}
+	if len(ts.mockQuizStore.Attempts) != 1 {
+		t.Errorf("expected 1 attempt, got %d", len(ts.mockQuizStore.Attempts))
+	}
+}
+
+func TestSubmitQuiz_MaxAttempts(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes[0].Settings.MaxAttempts = 1
+	submitted := time.Now()
+	ts.mockQuizStore.Attempts = []schema.QuizAttempt{{ID: 1, UserID: 1, QuizID: 1, SubmittedAt: &submitted}}
+
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, newSubmitRequest(map[string]any{"quiz_id": 1, "answers": []string{"Paris", "7"}}))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+}
+
+func TestSubmitQuiz_LatePolicy(t *testing.T) {
+	tests := []struct {
+		policy     schema.LatePolicy
+		penalty    float64
+		wantStatus int
+		wantScore  float64
+	}{
+		{schema.LateReject, 0, http.StatusForbidden, 0},
+		{schema.LateAccept, 0, http.StatusOK, 2},
+		{schema.LatePenalize, 0.25, http.StatusOK, 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			ts := newTestServer()
+			ts.mockQuizStore.Quizzes[0].Settings = schema.QuizSettings{TimeLimitMinutes: 10, LatePolicy: tt.policy, LatePenalty: tt.penalty}
+			deadline := time.Now().Add(-time.Hour)
+			ts.mockQuizStore.Attempts = []schema.QuizAttempt{{ID: 1, UserID: 1, QuizID: 1, Deadline: &deadline}}
+
+			rr := httptest.NewRecorder()
+			ts.submitQuiz(rr, newSubmitRequest(map[string]any{"quiz_id": 1, "answers": []string{"Paris", "7"}}))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			stored := ts.mockQuizStore.Attempts[0]
+			if stored.SubmittedAt == nil || stored.Score != tt.wantScore {
+				t.Errorf("expected the attempt to be closed with score %v, got %+v", tt.wantScore, stored)
+			}
+			if tt.wantStatus == http.StatusOK && !stored.Late {
+				t.Errorf("expected the attempt to be flagged as late")
+			}
+		})
+	}
+}
+
+func TestPutQuizSettings(t *testing.T) {
+	opens := time.Now()
+	closes := opens.Add(-time.Hour)
+	tests := []struct {
+		name       string
+		body       map[string]any
+		wantStatus int
+	}{
+		{"valid", map[string]any{"time_limit_minutes": 20, "max_attempts": 2, "late_policy": "penalize", "late_penalty": 0.1}, http.StatusOK},
+		{"closes before opens", map[string]any{"opens_at": opens, "closes_at": closes}, http.StatusBadRequest},
+		{"unknown policy", map[string]any{"late_policy": "MAYBE"}, http.StatusBadRequest},
+		{"penalty without policy", map[string]any{"late_penalty": 0.5}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer()
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("PUT", "/api/v1/quiz/1/settings", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			ctx := context.WithValue(req.Context(), "userID", "test-uid")
+			req = req.WithContext(context.WithValue(ctx, "userRole", schema.Educator))
+			rr := httptest.NewRecorder()
+			ts.putQuizSettings(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && ts.mockQuizStore.Quizzes[0].Settings.LatePolicy != schema.LatePenalize {
+				t.Errorf("expected the settings to be saved, got %+v", ts.mockQuizStore.Quizzes[0].Settings)
+			}
+		})
+	}
+}
+
+func TestSubmitQuiz_ScoringRules(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes[0].Settings.Scoring = schema.ScoringRules{NegativeMarking: 0.5, PassingScore: 0.5}
+	ts.mockQuizStore.Quizzes[0].Questions[1].Points = 3
+
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, newSubmitRequest(map[string]any{"quiz_id": 1, "answers": []string{"Rome", "7"}}))
+
+	var attempt schema.QuizAttempt
+	if err := json.Unmarshal(rr.Body.Bytes(), &attempt); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if attempt.Score != 2.5 || attempt.MaxScore != 4 {
+		t.Errorf("expected score 2.5/4, got %v/%v", attempt.Score, attempt.MaxScore)
+	}
+	if attempt.Answers[0].Points != -0.5 || attempt.Answers[1].Points != 3 {
+		t.Errorf("unexpected points per question %+v", attempt.Answers)
+	}
+	if attempt.Passed == nil || !*attempt.Passed {
+		t.Errorf("expected the attempt to pass, got %v", attempt.Passed)
+	}
+}
+
+func TestPutQuizPoints(t *testing.T) {
+	tests := []struct {
+		points     []float64
+		wantStatus int
+	}{
+		{[]float64{1, 2.5}, http.StatusOK},
+		{[]float64{1}, http.StatusBadRequest},
+		{[]float64{1, 0}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		ts := newTestServer()
+		body, _ := json.Marshal(map[string]any{"points": tt.points})
+		req := httptest.NewRequest("PUT", "/api/v1/quiz/1/points", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+		rr := httptest.NewRecorder()
+		ts.putQuizPoints(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("points %v: expected status %d, got %d", tt.points, tt.wantStatus, rr.Code)
+		}
+		if tt.wantStatus == http.StatusOK && ts.mockQuizStore.Quizzes[0].Questions[1].Points != 2.5 {
+			t.Errorf("expected the points to be saved, got %+v", ts.mockQuizStore.Quizzes[0].Questions)
+		}
+	}
+}
+
+func newGradebookRequest(query string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/v1/courses/1/gradebook"+query, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	return req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+}
+
+func TestGetGradebook_Policies(t *testing.T) {
+	tests := []struct {
+		policy    string
+		wantScore float64
+	}{
+		{"best", 2},
+		{"latest", 1},
+		{"average", 1.5},
+	}
+	for _, tt := range tests {
+		ts := newTestServer()
+		ts.mockEnrollmentStore.Enrollments = append(ts.mockEnrollmentStore.Enrollments,
+			schema.Enrollment{ID: 2, UserID: 2, CourseID: 1, Status: schema.EnrollmentActive, User: schema.User{ID: 2, Name: "Other"}})
+		latest, earlier := time.Now(), time.Now().Add(-time.Hour)
+		// attempts are listed latest first
+		ts.mockQuizStore.Attempts = []schema.QuizAttempt{
+			{ID: 2, UserID: 1, QuizID: 1, Score: 1, MaxScore: 2, SubmittedAt: &latest},
+			{ID: 1, UserID: 1, QuizID: 1, Score: 2, MaxScore: 2, SubmittedAt: &earlier},
+		}
+
+		rr := httptest.NewRecorder()
+		ts.getGradebook(rr, newGradebookRequest("?policy="+tt.policy))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200 OK, got %d: %s", tt.policy, rr.Code, rr.Body.String())
+		}
+		var book gradebook
+		if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(book.Quizzes) != 1 || book.Quizzes[0].MaxScore != 2 || len(book.Students) != 2 {
+			t.Fatalf("%s: unexpected gradebook %+v", tt.policy, book)
+		}
+		grade := book.Students[0].Grades[0]
+		if grade == nil || grade.Score != tt.wantScore || grade.Attempts != 2 {
+			t.Errorf("%s: expected score %v over 2 attempts, got %+v", tt.policy, tt.wantScore, grade)
+		}
+		if book.Students[0].Percent != tt.wantScore*50 {
+			t.Errorf("%s: expected %v%%, got %v", tt.policy, tt.wantScore*50, book.Students[0].Percent)
+		}
+		if book.Students[1].Grades[0] != nil || book.Students[1].Total != 0 {
+			t.Errorf("%s: expected no grade for a student who never attempted the quiz, got %+v", tt.policy, book.Students[1])
+		}
+	}
+}
+
+func TestGetGradebook_AdaptiveQuiz(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuizStore.Quizzes = append(ts.mockQuizStore.Quizzes, schema.Quiz{ID: 2, CourseID: 1, Adaptive: true, AdaptiveLength: 2, Questions: quizQuestions(
+		schema.Question{ID: 1}, schema.Question{ID: 2}, schema.Question{ID: 3}, schema.Question{ID: 4},
+	)})
+	submitted := time.Now()
+	ts.mockQuizStore.Attempts = []schema.QuizAttempt{{ID: 1, UserID: 1, QuizID: 2, Score: 1, MaxScore: 2, SubmittedAt: &submitted}}
+
+	rr := httptest.NewRecorder()
+	ts.getGradebook(rr, newGradebookRequest(""))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var book gradebook
+	if err := json.Unmarshal(rr.Body.Bytes(), &book); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(book.Quizzes) != 2 || book.Quizzes[1].MaxScore != 2 {
+		t.Fatalf("expected the adaptive quiz to be scored out of the 2 questions it serves, got %+v", book.Quizzes)
+	}
+	student := book.Students[0]
+	if student.Total != 1 || student.MaxTotal != 4 || student.Percent != 25 {
+		t.Errorf("expected 1 point out of 4, got %+v", student)
+	}
+}
+
+func TestGetGradebook_CSV(t *testing.T) {
+	ts := newTestServer()
+	ts.mockEnrollmentStore.Enrollments[0].User = schema.User{ID: 1, Name: "=HYPERLINK()", Email: "test@example.com"}
+	submitted := time.Now()
+	ts.mockQuizStore.Attempts = []schema.QuizAttempt{{ID: 1, UserID: 1, QuizID: 1, Score: 1, MaxScore: 2, SubmittedAt: &submitted}}
+
+	rr := httptest.NewRecorder()
+	ts.getGradebook(rr, newGradebookRequest("?format=csv"))
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("expected a CSV file, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	want := "user_id,name,email,status,quiz 1 (/2),total,max_total,percent\n" +
+		"1,'=HYPERLINK(),test@example.com,ACTIVE,1,1,2,50\n"
+	if rr.Body.String() != want {
+		t.Errorf("expected CSV\n%s\ngot\n%s", want, rr.Body.String())
+	}
+}
+
+func TestGetGradebook_NotOwner(t *testing.T) {
+	ts := newTestServer()
+	ts.mockCourseStore.Courses[0].UserID = 2
+
+	rr := httptest.NewRecorder()
+	ts.getGradebook(rr, newGradebookRequest(""))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 Forbidden, got %d", rr.Code)
+	}
+}
+
+func TestUpdateCourse_GradePolicy(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.updateCourse(rr, newCourseRequest("PATCH", "1", map[string]string{"grade_policy": "sometimes"}))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for an unknown policy, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	ts.updateCourse(rr, newCourseRequest("PATCH", "1", map[string]string{"grade_policy": "latest"}))
+	if rr.Code != http.StatusOK || ts.mockCourseStore.Courses[0].GradePolicy != schema.GradeLatest {
+		t.Errorf("expected the policy to be saved, got %d %q", rr.Code, ts.mockCourseStore.Courses[0].GradePolicy)
+	}
+}
+
+func TestAnalyzeItems(t *testing.T) {
+	question := schema.Question{ID: 1, Question: "Capital of France?", Options: []string{"Paris", "Rome", "Berlin"}, Answer: "Paris"}
+	seconds := func(s float64) *float64 { return &s }
+	// the best attempts got the question right, the worst ones did not
+	responses := []store.ItemResponse{
+		{QuestionID: 1, Answer: "Paris", Correct: true, Score: 9, MaxScore: 10, TimeSpent: seconds(10)},
+		{QuestionID: 1, Answer: "paris", Correct: true, Score: 8, MaxScore: 10, TimeSpent: seconds(20)},
+		{QuestionID: 1, Answer: "Paris", Correct: true, Score: 6, MaxScore: 10},
+		{QuestionID: 1, Answer: "Rome", Correct: false, Score: 4, MaxScore: 10},
+		{QuestionID: 1, Answer: "", Correct: false, Score: 2, MaxScore: 10},
+		{QuestionID: 1, Answer: "Rome", Correct: false, Score: 1, MaxScore: 10},
+		{QuestionID: 2, Answer: "7", Correct: true, Score: 1, MaxScore: 10},
+	}
+
+	stats := analyzeItems([]schema.Question{question}, responses)
+	if len(stats) != 1 {
+		t.Fatalf("expected stats for 1 question, got %d", len(stats))
+	}
+	item := stats[0]
+	if item.Responses != 6 || item.Blank != 1 || item.PercentCorrect == nil || *item.PercentCorrect != 50 {
+		t.Errorf("unexpected difficulty %+v", item)
+	}
+	if item.Discrimination == nil || *item.Discrimination != 1 {
+		t.Errorf("expected a discrimination of 1, got %v", item.Discrimination)
+	}
+	wantOptions := []optionStats{{"Paris", true, 3}, {"Rome", false, 2}, {"Berlin", false, 0}}
+	if !slices.Equal(item.Options, wantOptions) {
+		t.Errorf("expected options %+v, got %+v", wantOptions, item.Options)
+	}
+	if item.AvgTimeSpent == nil || *item.AvgTimeSpent != 15 {
+		t.Errorf("expected an average of 15 seconds, got %v", item.AvgTimeSpent)
+	}
+	if !slices.Equal(item.Flags, []string{"unused_distractor"}) {
+		t.Errorf("expected the unused distractor to be flagged, got %v", item.Flags)
+	}
+}
+
+func TestGetQuestionAnalytics_Flagged(t *testing.T) {
+	ts := newTestServer()
+	for range minFlagResponses {
+		ts.mockQuizStore.ItemResponses = append(ts.mockQuizStore.ItemResponses, store.ItemResponse{QuestionID: 2, Answer: "Pacific", Correct: true, Score: 1, MaxScore: 1})
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/1/analytics/questions?flagged=true", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	rr := httptest.NewRecorder()
+	ts.getQuestionAnalytics(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var stats []itemStats
+	json.Unmarshal(rr.Body.Bytes(), &stats)
+	if len(stats) != 1 || stats[0].QuestionID != 2 || !slices.Contains(stats[0].Flags, "too_easy") {
+		t.Errorf("expected only question 2 to be flagged as too easy, got %+v", stats)
+	}
+}
+
+func TestSubmitQuiz_TimeSpent(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.submitQuiz(rr, newSubmitRequest(map[string]any{"quiz_id": 1, "answers": []string{"Paris", "7"}, "time_spent": []float64{12, 30.5}}))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	answers := ts.mockQuizStore.Attempts[0].Answers
+	if answers[0].TimeSpent == nil || *answers[0].TimeSpent != 12 || answers[1].TimeSpent == nil || *answers[1].TimeSpent != 30.5 {
+		t.Errorf("expected the time spent to be recorded, got %+v", answers)
+	}
+
+	rr = httptest.NewRecorder()
+	ts.submitQuiz(rr, newSubmitRequest(map[string]any{"quiz_id": 1, "answers": []string{"Paris"}, "time_spent": []float64{-1}}))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request for a negative time, got %d", rr.Code)
+	}
+}
+
+func newImportRequest(query, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/v1/courses/1/questions/import"+query, strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	return req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+}
+
+const importGIFT = `What is 2 + 2? {=4 ~3 ~5}
+
+Water boils at 100 degrees {T}
+
+// [difficulty:extreme]
+Which is a vowel? {=a ~b}
+`
+
+func TestImportQuestions_DryRun(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.importQuestions(rr, newImportRequest("?format=gift&dry_run=true", importGIFT))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report importReport
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	if report.Parsed != 3 || report.Imported != 0 || len(report.Questions) != 2 {
+		t.Errorf("expected 3 parsed and 2 valid questions, got %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Line != 6 {
+		t.Errorf("expected an error on line 6, got %+v", report.Errors)
+	}
+	if len(ts.mockQuestionStore.Questions) != 3 {
+		t.Errorf("expected a dry run not to import anything")
+	}
+}
+
+func TestImportQuestions_NothingImportedOnErrors(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.importQuestions(rr, newImportRequest("?format=gift", importGIFT))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+	if len(ts.mockQuestionStore.Questions) != 3 {
+		t.Errorf("expected no question to be imported")
+	}
+}
+
+func TestImportQuestions_Success(t *testing.T) {
+	ts := newTestServer()
+
+	body := "question,type,answer,tolerance,difficulty,tags\nSpeed of light in km/s,numeric,299792,1,hard,Physics\n"
+	rr := httptest.NewRecorder()
+	ts.importQuestions(rr, newImportRequest("?format=csv", body))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(ts.mockQuestionStore.Questions) != 4 {
+		t.Fatalf("expected the question to be imported, got %d questions", len(ts.mockQuestionStore.Questions))
+	}
+	imported := ts.mockQuestionStore.Questions[3]
+	if imported.CourseID != 1 || imported.Type != schema.Numeric || imported.Difficulty != schema.Hard || imported.Tags[0].Name != "physics" {
+		t.Errorf("unexpected imported question %+v", imported)
+	}
+}
+
+func TestImportQuestions_InvalidFormat(t *testing.T) {
+	ts := newTestServer()
+
+	rr := httptest.NewRecorder()
+	ts.importQuestions(rr, newImportRequest("?format=xml", "<questions/>"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 Bad Request, got %d", rr.Code)
+	}
+}
+
+func TestExportQuestions(t *testing.T) {
+	ts := newTestServer()
+	ts.mockQuestionStore.Questions = append(ts.mockQuestionStore.Questions,
+		schema.Question{ID: 4, CourseID: 1, Type: schema.Ordering, Question: "Order", Options: []string{"a", "b"}, Answers: []string{"a", "b"}})
+
+	req := httptest.NewRequest("GET", "/api/v1/courses/1/questions/export?format=aiken&difficulty=easy", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr := httptest.NewRecorder()
+	ts.exportQuestions(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "ANSWER: B") || strings.Contains(rr.Body.String(), "largest ocean") {
+		t.Errorf("expected the easy questions in aiken, got %s", rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/courses/1/questions/export?format=gift", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	rr = httptest.NewRecorder()
+	ts.exportQuestions(rr, req)
+	if got := rr.Header().Get("X-Skipped-Questions"); got != "4" {
+		t.Errorf("expected the ordering question to be skipped, got %q", got)
+	}
+}
+
+func TestExportQuestions_OnlyOwner(t *testing.T) {
+	ts := newTestServer()
+
+	for id, status := range map[string]int{"3": http.StatusForbidden, "9": http.StatusNotFound} {
+		req := httptest.NewRequest("GET", "/api/v1/courses/"+id+"/questions/export?format=gift", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		ts.exportQuestions(rr, req)
+		if rr.Code != status {
+			t.Errorf("expected status %d for course %s, got %d", status, id, rr.Code)
+		}
+	}
+}
+
+func TestExportQuiz_QTIImportsBack(t *testing.T) {
+	ts := newTestServer()
+
+	req := httptest.NewRequest("GET", "/api/v1/quiz/1/export", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	req = req.WithContext(context.WithValue(req.Context(), "userID", "test-uid"))
+	rr := httptest.NewRecorder()
+	ts.exportQuiz(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Content-Type") != "application/zip" {
+		t.Errorf("expected a zip package, got %s", rr.Header().Get("Content-Type"))
+	}
+
+	rr2 := httptest.NewRecorder()
+	ts.importQuestions(rr2, newImportRequest("?format=qti", rr.Body.String()))
+	if rr2.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr2.Code, rr2.Body.String())
+	}
+	imported := ts.mockQuestionStore.Questions[3:]
+	if len(imported) != 2 || imported[0].Question != "What is the capital of France?" || imported[1].Answer != "7" {
+		t.Errorf("expected the questions of the quiz to be imported, got %+v", imported)
+	}
+}
+
+// Tests for the lifecycle of the server
+func TestServe_DrainsRequestsInFlight(t *testing.T) {
+	ts := newTestServer()
+	ts.shutdownTimeout = time.Second
+	var events []string
+	var mu sync.Mutex
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	ts.onShutdown("database", func() error { record("closed"); return nil })
+
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		record("served")
+		w.WriteHeader(http.StatusOK)
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ts.serve(ctx, server, listener) }()
+
+	response := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			response <- 0
+			return
+		}
+		resp.Body.Close()
+		response <- resp.StatusCode
+	}()
+	<-started
+	cancel()
+
+	if code := <-response; code != http.StatusOK {
+		t.Errorf("expected the request in flight to complete, got status %d", code)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+	if !slices.Equal(events, []string{"served", "closed"}) {
+		t.Errorf("expected the database to be closed after the request, got %v", events)
+	}
+}
+
+func TestServe_DrainTimeout(t *testing.T) {
+	ts := newTestServer()
+	ts.shutdownTimeout = 10 * time.Millisecond
+	closed := false
+	ts.onShutdown("database", func() error { closed = true; return nil })
+
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done() // never completes on its own
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ts.serve(ctx, server, listener) }()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+	cancel()
+
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "failed to drain requests") {
+		t.Errorf("expected the drain to time out, got %v", err)
+	}
+	if !closed {
+		t.Errorf("expected the database to be closed anyway")
+	}
+}
+
+func TestServe_ReturnsServeErrors(t *testing.T) {
+	ts := newTestServer()
+	ts.onShutdown("database", func() error { return errors.New("busy") })
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+
+	err = ts.serve(context.Background(), &http.Server{}, listener)
+	if err == nil || !strings.Contains(err.Error(), "server failed") || !strings.Contains(err.Error(), "failed to close database: busy") {
+		t.Errorf("expected the serve and close errors, got %v", err)
+	}
+}
+
+// Tests for the probes
+func TestProbes_NotRateLimited(t *testing.T) {
+	ts := newTestServer()
+	handler := ts.routes()
+
+	for _, path := range []string{"/healthz", "/healthz", "/healthz", "/version", "/version"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200 OK, got %d: %s", path, rr.Code, rr.Body.String())
+		}
+	}
+	var info version.Info
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/version", nil))
+	if err := json.NewDecoder(rr.Body).Decode(&info); err != nil || info.GoVersion == "" {
+		t.Errorf("expected the go version, got %+v: %v", info, err)
+	}
+}
+
+func TestReady(t *testing.T) {
+	ts := newTestServer()
+	ready := func() (int, map[string]string) {
+		rr := httptest.NewRecorder()
+		ts.getReady(rr, httptest.NewRequest("GET", "/readyz", nil))
+		var body struct {
+			Checks  map[string]string `json:"checks"`
+			Details map[string]string `json:"details"`
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body.Checks == nil {
+			body.Checks = body.Details
+		}
+		return rr.Code, body.Checks
+	}
+
+	if code, checks := ready(); code != http.StatusServiceUnavailable || checks["database"] == "ok" || checks["auth"] == "ok" {
+		t.Errorf("expected no database and no auth provider, got %d %v", code, checks)
+	}
+
+	t.Chdir(t.TempDir())
+	empty, err := gorm.Open(sqlite.Open("empty.sqlite3"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.db, ts.authenticator = empty, &auth.Local{}
+	if code, checks := ready(); code != http.StatusServiceUnavailable || checks["database"] != "ok" || !strings.Contains(checks["migrations"], "is missing") {
+		t.Errorf("expected the migrations to be missing, got %d %v", code, checks)
+	}
+
+	if ts.db, err = db.NewDB(); err != nil {
+		t.Fatal(err)
+	}
+	if code, checks := ready(); code != http.StatusOK || checks["migrations"] != "ok" || checks["auth"] != "ok" {
+		t.Errorf("expected the service to be ready, got %d %v", code, checks)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	ts := newTestServer()
+	handler := ts.routes()
+
+	// the second request within a second is rate limited
+	for range 2 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/courses/7/modules", nil))
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rr.Code)
+	}
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/api/v1/courses/{id}/modules",status="401"} 1`,
+		`http_requests_total{method="GET",route="/api/v1/courses/{id}/modules",status="429"} 1`,
+		`http_rate_limited_requests_total{route="/api/v1/courses/{id}/modules"} 1`,
+	} {
+		if !strings.Contains(rr.Body.String(), line) {
+			t.Errorf("expected %q in the metrics", line)
+		}
+	}
+}
+
+func TestTracing_SpansEveryLayer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Chdir(t.TempDir())
+	database, err := db.NewDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Use(telemetry.GORMPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	local, err := auth.NewLocal(database, "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := local.CreateUser(context.Background(), "student@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Create(&schema.User{UID: record.UID, Email: record.Email, Role: schema.Student})
+	token, err := local.SignIn(context.Background(), "student@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer()
+	ts.db, ts.authenticator = database, local
+	ts.courseStore = store.NewCourseStore(store.NewStore(database, ts.logger))
+
+	req := httptest.NewRequest("GET", "/api/v1/courses", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	ts.routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	parents := map[string]string{}
+	ids := map[string]string{}
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected %s to continue the incoming trace", span.Name())
+		}
+		ids[span.SpanContext().SpanID().String()] = span.Name()
+		parents[span.Name()] = span.Parent().SpanID().String()
+	}
+	for child, parent := range map[string]string{
+		"authMiddleware":          "GET /api/v1/courses",
+		"RBACMiddleware":          "GET /api/v1/courses",
+		"getCourses":              "GET /api/v1/courses",
+		"CourseStore.ListCourses": "getCourses",
+		"gorm.query":              "CourseStore.ListCourses",
+	} {
+		if got := ids[parents[child]]; got != parent {
+			t.Errorf("expected %s to be a child of %s, got %q", child, parent, got)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	ts := newTestServer()
+	log, err := logger.NewLogger(logger.JSON, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	ts.logger = log
+	handler := ts.routes()
+
+	req := httptest.NewRequest("GET", "/api/v1/courses", nil)
+	req.Header.Set(utils.RequestIDHeader, "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected only the api request to be logged at info level, got %q", out.String())
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{"msg": "request served", "method": "GET", "path": "/api/v1/courses", "status": 401.0, "request_id": "req-42"} {
+		if line[key] != want {
+			t.Errorf("expected %s to be %v, got %v", key, want, line[key])
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/db"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/version"
+)
+
+// readyTimeout bounds how long the readiness checks wait on the database
+const readyTimeout = 2 * time.Second
+
+// Handler for the liveness probe, the process is up as long as it answers
+func (s *Server) getHealth(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Handler for the readiness probe, the service is ready once its dependencies are
+// Every check is reported, a failing one makes it answer 503
+func (s *Server) getReady(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"database":   "ok",
+		"migrations": "ok",
+		"auth":       "ok",
+	}
+	ready := true
+	fail := func(check string, err error) {
+		checks[check], ready = err.Error(), false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := s.pingDB(ctx); err != nil {
+		fail("database", err)
+		fail("migrations", errors.New("the database is not reachable"))
+	} else if !s.migrated.Load() {
+		if err := db.Migrated(s.db.WithContext(ctx)); err != nil {
+			fail("migrations", err)
+		} else {
+			s.migrated.Store(true) // migrations are not undone while the server runs
+		}
+	}
+	if s.authenticator == nil {
+		fail("auth", errors.New("the auth provider is not initialised"))
+	}
+
+	if !ready {
+		utils.WriteErrorDetails(w, "the service is not ready", http.StatusServiceUnavailable, checks)
+		return
+	}
+	utils.WriteJSONResponse(w, map[string]any{"status": "ready", "checks": checks})
+}
+
+func (s *Server) pingDB(ctx context.Context) error {
+	if s.db == nil {
+		return errors.New("no database")
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Handler returning the build of the running server
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, version.Get())
+}
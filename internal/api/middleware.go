@@ -2,45 +2,71 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"slices"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+	"github.com/sirupsen/logrus"
 	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/middleware/stdlib"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
+// tracer opens the spans of the middlewares and handlers, children of the server span of the request
+var tracer = otel.Tracer("github.com/rudransh-shrivastava/rudransh-backend-task/internal/api")
+
+// traced opens a span named after the handler around it
+func traced(name string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), name)
+		defer span.End()
+		h(w, r.WithContext(ctx))
+	})
+}
+
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idToken := r.Header.Get("Authorization")
 		if idToken == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			utils.WriteErrorResponse(w, "Missing Authorization header", http.StatusUnauthorized)
 			return
 		}
 
 		// Properly parse Bearer token
 		parts := strings.Split(idToken, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(w, "Invalid Authorization format, expected 'Bearer <token>'", http.StatusUnauthorized)
+			utils.WriteErrorResponse(w, "Invalid Authorization format, expected 'Bearer <token>'", http.StatusUnauthorized)
 			return
 		}
 
 		tokenStr := parts[1]
 
-		// Verify the token with Firebase
-		token, err := s.authClient.VerifyIDToken(context.Background(), tokenStr)
+		// Verify the token with the configured auth provider
+		// The span only covers the verification, the rest of the request is not part of it
+		ctx, span := tracer.Start(r.Context(), "authMiddleware")
+		uid, err := s.authenticator.VerifyToken(ctx, tokenStr)
 		if err != nil {
-			s.logger.Errorf("Token verification failed: %v", err)
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "token verification failed")
+			span.End()
+			s.log(r).Errorf("Token verification failed: %v", err)
+			utils.WriteErrorResponse(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
+		span.End()
 
-		ctx := context.WithValue(r.Context(), "userID", token.UID)
+		ctx = context.WithValue(r.Context(), "userID", uid)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -52,7 +78,16 @@ func (s *Server) newRateLimitMiddleware() mux.MiddlewareFunc {
 	}
 	store := memory.NewStore()
 	rateLimiter := limiter.New(store, rate)
-	middleware := stdlib.NewMiddleware(rateLimiter)
+	middleware := stdlib.NewMiddleware(rateLimiter,
+		stdlib.WithLimitReachedHandler(func(w http.ResponseWriter, r *http.Request) {
+			s.metrics.RateLimited(r)
+			utils.WriteErrorResponse(w, "Too many requests, slow down", http.StatusTooManyRequests)
+		}),
+		stdlib.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			s.log(r).Errorf("Rate limiter failed: %v", err)
+			utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		}),
+	)
 
 	return middleware.Handler
 }
@@ -63,33 +98,96 @@ func RBACMiddleware(db *gorm.DB, allowedRoles ...schema.Role) func(http.Handler)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			uid, ok := r.Context().Value("userID").(string)
 			if !ok || uid == "" {
-				http.Error(w, "Unauthorized: missing user ID", http.StatusUnauthorized)
+				utils.WriteErrorResponse(w, "Unauthorized: missing user ID", http.StatusUnauthorized)
 				return
 			}
 
+			// The span only covers the role check, the handler gets its own
+			ctx, span := tracer.Start(r.Context(), "RBACMiddleware")
 			var user schema.User
-			if err := db.Where("uid = ?", uid).First(&user).Error; err != nil {
-				http.Error(w, "User not found", http.StatusUnauthorized)
+			if err := db.WithContext(ctx).Where("uid = ?", uid).First(&user).Error; err != nil {
+				span.End()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					utils.WriteErrorResponse(w, "User not found", http.StatusUnauthorized)
+					return
+				}
+				utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 
 			// Check if the user's role is allowed.
 			allowed := slices.Contains(allowedRoles, user.Role)
+			span.SetAttributes(attribute.String("user.role", string(user.Role)), attribute.Bool("rbac.allowed", allowed))
+			span.End()
 			if !allowed {
-				http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+				utils.WriteErrorResponse(w, "Forbidden: insufficient permissions", http.StatusForbidden)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			// The role is kept in the context so responses can be projected per role
+			ctx = context.WithValue(r.Context(), "userRole", user.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// requestIDMiddleware tags every request with an id, echoed in the X-Request-ID header and in error responses
+// An id sent by the client or a proxy in front of us is kept if it looks sane
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(utils.RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(utils.RequestIDHeader, id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request.id", id))
+		ctx := context.WithValue(r.Context(), "requestID", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// accessLogMiddleware logs every request once it is served
+// The probes are only logged at debug level so their polling does not drown the other requests
+func (s *Server) accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &utils.ResponseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		entry := s.log(r).WithFields(logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      recorder.StatusCode(),
+			"bytes":       recorder.Bytes,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		})
+		if slices.Contains(probePaths, r.URL.Path) {
+			entry.Debug("request served")
+			return
+		}
+		entry.Info("request served")
+	})
+}
+
 // The corsMiddleware adds the necessary headers to enable CORS
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "*")
 
 		if r.Method == "OPTIONS" {
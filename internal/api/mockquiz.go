@@ -1,43 +1,25 @@
 package api
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"math/rand"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/generator"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
 )
 
-type Question struct {
-	Question string   `json:"question"`
-	Options  []string `json:"options"`
-	Answer   string   `json:"answer"`
-}
-
-// list of available questions
-var quizPool = []Question{
-	{"What is the capital of France?", []string{"Berlin", "Madrid", "Paris", "Rome"}, "Paris"},
-	{"Which planet is known as the Red Planet?", []string{"Earth", "Mars", "Jupiter", "Venus"}, "Mars"},
-	{"Who wrote 'Romeo and Juliet'?", []string{"Shakespeare", "Dickens", "Hemingway", "Fitzgerald"}, "Shakespeare"},
-	{"What is the largest ocean on Earth?", []string{"Atlantic", "Indian", "Arctic", "Pacific"}, "Pacific"},
-	{"What is the tallest mountain in the world?", []string{"K2", "Mount Everest", "Kangchenjunga", "Lhotse"}, "Mount Everest"},
-	{"What is the smallest country in the world?", []string{"Vatican City", "Monaco", "San Marino", "Liechtenstein"}, "Vatican City"},
-	{"What gas do plants absorb from the atmosphere?", []string{"Oxygen", "Carbon Dioxide", "Nitrogen", "Hydrogen"}, "Carbon Dioxide"},
-	{"How many continents are there?", []string{"5", "6", "7", "8"}, "7"},
-	{"What is the fastest land animal?", []string{"Cheetah", "Lion", "Horse", "Kangaroo"}, "Cheetah"},
-	{"Which element has the chemical symbol 'O'?", []string{"Oxygen", "Gold", "Silver", "Osmium"}, "Oxygen"},
-}
-
-// mock: returns a quiz of the given size
-func generateQuiz(number int) ([]Question, error) {
-	if number > len(quizPool) || number <= 0 {
-		return nil, fmt.Errorf("invalid quiz size: must be between 1 and %d", len(quizPool))
-	}
-	return quizPool[:number], nil
-}
-
 func (s *Server) generateQuiz(w http.ResponseWriter, r *http.Request) {
 	if r.Header.Get("Content-Type") != "application/json" {
 		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
@@ -48,21 +30,32 @@ func (s *Server) generateQuiz(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		CourseID string `json:"course_id"`
-		Number   string `json:"number"`
+		CourseID   string              `json:"course_id"`
+		Number     string              `json:"number"`
+		Tag        string              `json:"tag"`        // optional, only pick questions with this tag
+		Difficulty string              `json:"difficulty"` // optional, only pick questions of this difficulty
+		Type       string              `json:"type"`       // optional, only pick questions of this type
+		Seed       *int64              `json:"seed"`       // optional, reuse the seed of a quiz to generate it again
+		Variants   bool                `json:"per_student_variants"`
+		Adaptive   bool                `json:"adaptive"`        // optional, serve the questions one at a time by the ability of the student
+		Length     int                 `json:"adaptive_length"` // optional, questions served in an adaptive attempt, all of them by default
+		Settings   schema.QuizSettings `json:"settings"`        // optional, when and how often the quiz can be taken
+		LessonID   *uint               `json:"lesson_id"`       // optional, attach the quiz to a lesson of the course
+		Points     *float64            `json:"points"`          // optional, points of every question, 1 by default
+		Generator  string              `json:"generator"`       // optional, "bank", "template" or "llm", the configured one by default
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 	if req.CourseID == "" || req.Number == "" {
-		http.Error(w, "Course ID and number are required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Course ID and number are required", http.StatusBadRequest)
 		return
 	}
 	number, err := strconv.Atoi(req.Number)
 	if err != nil {
-		http.Error(w, "Invalid number, must be a number", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Invalid number, must be a number", http.StatusBadRequest)
 		return
 	}
 
@@ -72,44 +65,178 @@ func (s *Server) generateQuiz(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	course, err := s.courseStore.GetCourseById(uint(courseId))
+	course, err := s.courseStore.GetCourseById(r.Context(), uint(courseId))
 	if err != nil {
-		utils.WriteErrorResponse(w, "course not found", http.StatusNotFound)
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
 		return
 	}
 
-	questions, err := generateQuiz(number)
-	if err != nil {
+	if req.LessonID != nil {
+		lesson, err := s.moduleStore.GetLessonById(r.Context(), *req.LessonID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if err != nil || lesson.Module.CourseID != course.ID {
+			utils.WriteErrorResponse(w, "lesson not found in this course", http.StatusNotFound)
+			return
+		}
+	}
+
+	if number <= 0 {
+		utils.WriteErrorResponse(w, "invalid quiz size: must be at least 1", http.StatusBadRequest)
+		return
+	}
+	points := 1.0
+	if req.Points != nil {
+		if points = *req.Points; points <= 0 {
+			utils.WriteErrorResponse(w, "points must be positive", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := validateAdaptive(req.Adaptive, req.Length, number, req.Variants); err != nil {
 		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	jsonQuestions, err := json.Marshal(questions)
+	if err := validateSettings(&req.Settings); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := store.QuestionFilter{CourseID: course.ID, Tag: strings.ToLower(req.Tag)}
+	if req.Difficulty != "" {
+		if filter.Difficulty, err = parseDifficulty(req.Difficulty); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Type != "" {
+		if filter.Type, err = parseQuestionType(req.Type); err != nil {
+			utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	generatorName := cmp.Or(req.Generator, s.quizGenerator)
+	quizGenerator, found := s.generators[generatorName]
+	if !found {
+		utils.WriteErrorResponse(w, fmt.Sprintf("unknown quiz generator %q, must be one of %s", generatorName, strings.Join(slices.Sorted(maps.Keys(s.generators)), ", ")), http.StatusBadRequest)
+		return
+	}
+	lessons, err := s.quizContent(r.Context(), course.ID, req.LessonID)
 	if err != nil {
 		utils.WriteErrorResponse(w, "failed to generate quiz", http.StatusInternalServerError)
 		return
 	}
+	seed := rand.Int63()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	generated, err := quizGenerator.Generate(r.Context(), generator.Request{
+		CourseID:   course.ID,
+		Lessons:    lessons,
+		Number:     number,
+		Tag:        filter.Tag,
+		Difficulty: filter.Difficulty,
+		Type:       filter.Type,
+		Seed:       seed,
+	})
+	var notEnough *generator.NotEnoughQuestionsError
+	switch {
+	case errors.As(err, &notEnough):
+		utils.WriteErrorDetails(w, fmt.Sprintf("invalid quiz size: %v", err), http.StatusBadRequest,
+			map[string]int{"requested": notEnough.Requested, "available": notEnough.Available})
+		return
+	case errors.Is(err, generator.ErrUnsupported):
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, generator.ErrUnavailable):
+		s.log(r).Errorf("Quiz generator %s failed for course %d: %v", generatorName, course.ID, err)
+		utils.WriteErrorResponse(w, "the quiz generator failed, try again later", http.StatusBadGateway)
+		return
+	case err != nil:
+		utils.WriteErrorResponse(w, "failed to generate quiz", http.StatusInternalServerError)
+		return
+	}
+
+	// questions written by the generator are added to the bank along with the quiz, the quiz references them there
+	for i := range generated {
+		if generated[i].ID != 0 {
+			continue
+		}
+		q := &generated[i]
+		if err := validateQuestion(q); err != nil {
+			s.log(r).Errorf("Quiz generator %s wrote an invalid question for course %d: %v", generatorName, course.ID, err)
+			utils.WriteErrorResponse(w, fmt.Sprintf("the quiz generator wrote an invalid question: %v", err), http.StatusBadGateway)
+			return
+		}
+		q.CourseID = course.ID
+		q.Tags = append(q.Tags, schema.QuestionTag{Name: generatedTag})
+	}
+	questions := make([]schema.QuizQuestion, 0, number)
+	for i, q := range generated {
+		questions = append(questions, schema.QuizQuestion{Position: i, Question: q, QuestionID: q.ID, Points: points})
+	}
+	shuffleOptions(questions, seed)
+
 	schemaQuiz := schema.Quiz{
-		Course:    *course,
-		Questions: string(jsonQuestions),
+		Course:             *course,
+		Questions:          questions,
+		Seed:               seed,
+		Tag:                filter.Tag,
+		Difficulty:         filter.Difficulty,
+		QuestionType:       filter.Type,
+		Generator:          generatorName,
+		PerStudentVariants: req.Variants,
+		Adaptive:           req.Adaptive,
+		AdaptiveLength:     req.Length,
+		LessonID:           req.LessonID,
+		Settings:           req.Settings,
 	}
-	err = s.quizStore.CreateQuiz(&schemaQuiz)
+	err = s.quizStore.CreateQuiz(r.Context(), &schemaQuiz)
 	if err != nil {
 		utils.WriteErrorResponse(w, "failed to create quiz", http.StatusInternalServerError)
 		return
 	}
-	s.logger.Debugf("Generated a quiz for course %d successfully", courseId)
+	s.metrics.QuizGenerated(generatorName)
+	s.log(r).Debugf("Generated a quiz for course %d with the %s generator and seed %d successfully", courseId, generatorName, seed)
+	s.writeQuizResponse(w, r, &schemaQuiz)
+}
+
+// generatedTag is added to the questions a generator writes, to review them in the bank
+const generatedTag = "generated"
+
+// quizContent returns the lessons a quiz is generated from, the given lesson or all the lessons of the course
+func (s *Server) quizContent(ctx context.Context, courseID uint, lessonID *uint) ([]schema.Lesson, error) {
+	if lessonID != nil {
+		lesson, err := s.moduleStore.GetLessonById(ctx, *lessonID)
+		if err != nil {
+			return nil, err
+		}
+		return []schema.Lesson{*lesson}, nil
+	}
+	modules, err := s.moduleStore.ListModules(ctx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	var lessons []schema.Lesson
+	for _, module := range modules {
+		lessons = append(lessons, module.Lessons...)
+	}
+	return lessons, nil
 }
 
 func (s *Server) getQuiz(w http.ResponseWriter, r *http.Request) {
 	queryCourseId := r.URL.Query().Get("course_id")
 	queryQuizId := r.URL.Query().Get("quiz_id")
 	if queryCourseId == "" {
-		http.Error(w, "Course ID is required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Course ID is required", http.StatusBadRequest)
 		return
 	}
 	if queryQuizId == "" {
-		http.Error(w, "Quiz ID is required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, "Quiz ID is required", http.StatusBadRequest)
 		return
 	}
 
@@ -125,24 +252,49 @@ func (s *Server) getQuiz(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = s.courseStore.GetCourseById(uint(courseId))
+	_, err = s.courseStore.GetCourseById(r.Context(), uint(courseId))
 	if err != nil {
-		utils.WriteErrorResponse(w, "course not found", http.StatusNotFound)
+		writeStoreError(w, err, "course")
 		return
 	}
 
-	quiz, err := s.quizStore.GetQuizById(uint(quizId))
+	quiz, err := s.quizStore.GetQuizById(r.Context(), uint(quizId))
 	if err != nil {
+		writeStoreError(w, err, "quiz")
+		return
+	}
+
+	if quiz.CourseID != uint(courseId) {
 		utils.WriteErrorResponse(w, "quiz not found", http.StatusNotFound)
 		return
 	}
 
 	user, err := s.userStore.GetUserFromContext(r.Context())
 	if err != nil {
-		utils.WriteErrorResponse(w, "user not found", http.StatusUnauthorized)
+		writeCurrentUserError(w, err)
 		return
 	}
+	if !s.requireEnrollment(w, r, user, quiz.CourseID) {
+		return
+	}
+
+	// Students get their own variant of the quiz if it was generated with variants
+	role := roleFromContext(r)
+	served, seed := quiz, int64(0)
+	if quiz.PerStudentVariants && !canSeeAnswers(role) {
+		seed = variantSeed(quiz.Seed, user.ID)
+		served = applyVariant(quiz, seed)
+	}
+	if quiz.Adaptive {
+		seed = variantSeed(quiz.Seed, user.ID) // breaks the ties between the questions getNextQuestion can serve
+	}
 
-	err = s.quizStore.RegisterQuizTaken(user, quiz)
-	utils.WriteJSONResponse(w, quiz)
+	// Record the start of an attempt unless one is already in progress
+	// The questions are only served when the settings of the quiz allow an attempt
+	if _, err := s.beginAttempt(r.Context(), quiz, attemptSettings(quiz, role), user.ID, seed, time.Now()); err != nil {
+		s.log(r).Errorf("Failed to start attempt of quiz %d: %v", quiz.ID, err)
+		writeAttemptError(w, err)
+		return
+	}
+	s.writeQuizResponse(w, r, served)
 }
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// lessonResponse is a lesson along with the quizzes attached to it
+type lessonResponse struct {
+	*schema.Lesson
+	CourseID uint   `json:"course_id"`
+	QuizIDs  []uint `json:"quiz_ids"`
+}
+
+func validateLesson(lesson *schema.Lesson) error {
+	lesson.Title = strings.TrimSpace(lesson.Title)
+	if lesson.Title == "" {
+		return errors.New("title is required")
+	}
+	if lesson.EstimatedMinutes < 0 {
+		return errors.New("estimated_minutes must not be negative")
+	}
+	return nil
+}
+
+// authorizeModuleOwner checks the current user can modify the course the module belongs to
+func (s *Server) authorizeModuleOwner(w http.ResponseWriter, r *http.Request, module *schema.Module) bool {
+	course, err := s.courseStore.GetCourseById(r.Context(), module.CourseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return false
+	}
+	return s.authorizeCourseOwner(w, r, course)
+}
+
+// decodeReorder reads the new order of a list of modules or lessons
+func decodeReorder(w http.ResponseWriter, r *http.Request) ([]uint, bool) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return nil, false
+	}
+	var req struct {
+		IDs []uint `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	return req.IDs, true
+}
+
+// Handler to get the outline of a course, its modules and their lessons in order
+func (s *Server) getModules(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+	if !s.requireEnrollment(w, r, user, course.ID) {
+		return
+	}
+
+	modules, err := s.moduleStore.ListModules(r.Context(), course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, modules)
+}
+
+// Handler to add a module at the end of a course
+func (s *Server) postModule(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var module schema.Module
+	if err := json.NewDecoder(r.Body).Decode(&module); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	module.Title = strings.TrimSpace(module.Title)
+	if module.Title == "" {
+		utils.WriteErrorResponse(w, "title is required", http.StatusBadRequest)
+		return
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+	// lessons are added through their own endpoint
+	module = schema.Module{CourseID: course.ID, Title: module.Title, Lessons: []schema.Lesson{}}
+
+	if err := s.moduleStore.CreateModule(r.Context(), &module); err != nil {
+		utils.WriteErrorResponse(w, "failed to create module", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, module)
+}
+
+// Handler to rename a module
+func (s *Server) putModule(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	module, err := s.moduleStore.GetModuleById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "module")
+		return
+	}
+	if !s.authorizeModuleOwner(w, r, module) {
+		return
+	}
+
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	module.Title = strings.TrimSpace(req.Title)
+	if module.Title == "" {
+		utils.WriteErrorResponse(w, "title is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.moduleStore.UpdateModule(r.Context(), module); err != nil {
+		utils.WriteErrorResponse(w, "failed to update module", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, module)
+}
+
+// Handler to delete a module along with its lessons
+// Quizzes attached to those lessons stay in the course
+func (s *Server) deleteModule(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	module, err := s.moduleStore.GetModuleById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "module")
+		return
+	}
+	if !s.authorizeModuleOwner(w, r, module) {
+		return
+	}
+
+	if err := s.moduleStore.DeleteModule(r.Context(), module); err != nil {
+		utils.WriteErrorResponse(w, "failed to delete module", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, module)
+}
+
+// Handler to change the order of the modules of a course
+// The body lists the ids of every module of the course in the new order
+func (s *Server) reorderModules(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids, ok := decodeReorder(w, r)
+	if !ok {
+		return
+	}
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	if err := s.moduleStore.ReorderModules(r.Context(), course.ID, ids); err != nil {
+		writeStoreError(w, err, "module")
+		return
+	}
+	modules, err := s.moduleStore.ListModules(r.Context(), course.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, modules)
+}
+
+// Handler to get a lesson and the ids of the quizzes attached to it
+func (s *Server) getLesson(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lesson, err := s.moduleStore.GetLessonById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "lesson")
+		return
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+	if !s.requireEnrollment(w, r, user, lesson.Module.CourseID) {
+		return
+	}
+
+	quizIDs, err := s.quizStore.ListLessonQuizIDs(r.Context(), lesson.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, lessonResponse{Lesson: lesson, CourseID: lesson.Module.CourseID, QuizIDs: quizIDs})
+}
+
+// Handler to add a lesson at the end of a module
+func (s *Server) postLesson(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	moduleID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var lesson schema.Lesson
+	if err := json.NewDecoder(r.Body).Decode(&lesson); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateLesson(&lesson); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	module, err := s.moduleStore.GetModuleById(r.Context(), moduleID)
+	if err != nil {
+		writeStoreError(w, err, "module")
+		return
+	}
+	if !s.authorizeModuleOwner(w, r, module) {
+		return
+	}
+	lesson.ID = 0
+	lesson.ModuleID = module.ID
+
+	if err := s.moduleStore.CreateLesson(r.Context(), &lesson); err != nil {
+		utils.WriteErrorResponse(w, "failed to create lesson", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, lesson)
+}
+
+// Handler to edit the title, body and estimated duration of a lesson
+func (s *Server) putLesson(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing, err := s.moduleStore.GetLessonById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "lesson")
+		return
+	}
+	if !s.authorizeModuleOwner(w, r, &existing.Module) {
+		return
+	}
+
+	var lesson schema.Lesson
+	if err := json.NewDecoder(r.Body).Decode(&lesson); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateLesson(&lesson); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing.Title = lesson.Title
+	existing.Body = lesson.Body
+	existing.EstimatedMinutes = lesson.EstimatedMinutes
+
+	if err := s.moduleStore.UpdateLesson(r.Context(), existing); err != nil {
+		utils.WriteErrorResponse(w, "failed to update lesson", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, existing)
+}
+
+// Handler to delete a lesson
+// Quizzes attached to the lesson stay in the course
+func (s *Server) deleteLesson(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lesson, err := s.moduleStore.GetLessonById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "lesson")
+		return
+	}
+	if !s.authorizeModuleOwner(w, r, &lesson.Module) {
+		return
+	}
+
+	if err := s.moduleStore.DeleteLesson(r.Context(), lesson); err != nil {
+		utils.WriteErrorResponse(w, "failed to delete lesson", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, lesson)
+}
+
+// Handler to change the order of the lessons of a module
+// The body lists the ids of every lesson of the module in the new order
+func (s *Server) reorderLessons(w http.ResponseWriter, r *http.Request) {
+	moduleID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids, ok := decodeReorder(w, r)
+	if !ok {
+		return
+	}
+	module, err := s.moduleStore.GetModuleById(r.Context(), moduleID)
+	if err != nil {
+		writeStoreError(w, err, "module")
+		return
+	}
+	if !s.authorizeModuleOwner(w, r, module) {
+		return
+	}
+
+	if err := s.moduleStore.ReorderLessons(r.Context(), module.ID, ids); err != nil {
+		writeStoreError(w, err, "lesson")
+		return
+	}
+	if module, err = s.moduleStore.GetModuleById(r.Context(), module.ID); err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, module)
+}
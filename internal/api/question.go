@@ -0,0 +1,341 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// pathID reads a numeric id from the route variables
+func pathID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil || id <= 0 {
+		return 0, errors.New("Invalid " + name + ", must be a number")
+	}
+	return uint(id), nil
+}
+
+func parseDifficulty(value string) (schema.Difficulty, error) {
+	difficulty := schema.Difficulty(strings.ToUpper(value))
+	switch difficulty {
+	case schema.Easy, schema.Medium, schema.Hard:
+		return difficulty, nil
+	}
+	return "", errors.New("difficulty must be one of EASY, MEDIUM or HARD")
+}
+
+func parseQuestionType(value string) (schema.QuestionType, error) {
+	questionType := schema.QuestionType(strings.ToUpper(value))
+	switch questionType {
+	case schema.SingleChoice, schema.MultiSelect, schema.TrueFalse, schema.Numeric, schema.ShortText, schema.Ordering, schema.Matching:
+		return questionType, nil
+	}
+	return "", errors.New("type must be one of SINGLE_CHOICE, MULTI_SELECT, TRUE_FALSE, NUMERIC, SHORT_TEXT, ORDERING or MATCHING")
+}
+
+// validateQuestion checks a question before it is written to the bank
+// A missing type defaults to SINGLE_CHOICE and a missing difficulty to MEDIUM
+func validateQuestion(q *schema.Question) error {
+	q.Question = strings.TrimSpace(q.Question)
+	if q.Question == "" {
+		return errors.New("question is required")
+	}
+	if q.Type == "" {
+		q.Type = schema.SingleChoice
+	}
+	questionType, err := parseQuestionType(string(q.Type))
+	if err != nil {
+		return err
+	}
+	q.Type = questionType
+	if err := validateAnswer(q); err != nil {
+		return err
+	}
+	if q.Difficulty == "" {
+		q.Difficulty = schema.Medium
+	}
+	difficulty, err := parseDifficulty(string(q.Difficulty))
+	if err != nil {
+		return err
+	}
+	q.Difficulty = difficulty
+	for i := range q.Tags {
+		q.Tags[i].Name = strings.ToLower(strings.TrimSpace(q.Tags[i].Name))
+		if q.Tags[i].Name == "" {
+			return errors.New("tags must not be empty")
+		}
+	}
+	return nil
+}
+
+// validateAnswer checks the answer of a question is consistent with its type
+// Fields the type does not use are cleared
+func validateAnswer(q *schema.Question) error {
+	switch q.Type {
+	case schema.SingleChoice:
+		if len(q.Options) < 2 {
+			return errors.New("at least two options are required")
+		}
+		if !slices.Contains(q.Options, q.Answer) {
+			return errors.New("answer must be one of the options")
+		}
+		q.Answers, q.Matches, q.Tolerance, q.Pattern = nil, nil, 0, ""
+
+	case schema.MultiSelect:
+		if len(q.Options) < 2 {
+			return errors.New("at least two options are required")
+		}
+		if len(q.Answers) == 0 {
+			return errors.New("answers must list the correct options")
+		}
+		for _, a := range q.Answers {
+			if !slices.Contains(q.Options, a) {
+				return errors.New("answers must be among the options")
+			}
+		}
+		q.Answer, q.Matches, q.Tolerance, q.Pattern = "", nil, 0, ""
+
+	case schema.TrueFalse:
+		q.Answer = strings.ToLower(strings.TrimSpace(q.Answer))
+		if q.Answer != "true" && q.Answer != "false" {
+			return errors.New("answer must be true or false")
+		}
+		q.Options = []string{"true", "false"}
+		q.Answers, q.Matches, q.Tolerance, q.Pattern = nil, nil, 0, ""
+
+	case schema.Numeric:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(q.Answer), 64); err != nil {
+			return errors.New("answer must be a number")
+		}
+		if q.Tolerance < 0 {
+			return errors.New("tolerance must not be negative")
+		}
+		q.Answer = strings.TrimSpace(q.Answer)
+		q.Options, q.Answers, q.Matches, q.Pattern = nil, nil, nil, ""
+
+	case schema.ShortText:
+		if len(q.Answers) == 0 && q.Pattern == "" {
+			return errors.New("answers or pattern is required")
+		}
+		if q.Pattern != "" {
+			if _, err := scoring.CompilePattern(q.Pattern); err != nil {
+				return errors.New("pattern is not a valid regular expression")
+			}
+		}
+		q.Answer, q.Options, q.Matches, q.Tolerance = "", nil, nil, 0
+
+	case schema.Ordering:
+		if len(q.Options) < 2 {
+			return errors.New("at least two options are required")
+		}
+		if len(q.Answers) == 0 {
+			// the options are given in the correct order
+			q.Answers = append([]string(nil), q.Options...)
+		}
+		if !scoring.SameSet(q.Answers, q.Options) || len(q.Answers) != len(q.Options) {
+			return errors.New("answers must list every option once, in the correct order")
+		}
+		q.Answer, q.Matches, q.Tolerance, q.Pattern = "", nil, 0, ""
+
+	case schema.Matching:
+		if len(q.Options) < 2 {
+			return errors.New("at least two options are required")
+		}
+		if len(q.Answers) != len(q.Options) {
+			return errors.New("answers must give the match of every option")
+		}
+		for _, a := range q.Answers {
+			if !slices.Contains(q.Matches, a) {
+				return errors.New("answers must be among the matches")
+			}
+		}
+		q.Answer, q.Tolerance, q.Pattern = "", 0, ""
+	}
+	return nil
+}
+
+// authorizeQuestionOwner checks the current user can modify the course the question belongs to
+func (s *Server) authorizeQuestionOwner(w http.ResponseWriter, r *http.Request, question *schema.Question) bool {
+	course, err := s.courseStore.GetCourseById(r.Context(), question.CourseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return false
+	}
+	return s.authorizeCourseOwner(w, r, course)
+}
+
+// parseQuestionFilter reads the tag, difficulty and type query params
+func parseQuestionFilter(r *http.Request, courseID uint) (store.QuestionFilter, error) {
+	var err error
+	filter := store.QuestionFilter{CourseID: courseID, Tag: strings.ToLower(r.URL.Query().Get("tag"))}
+	if d := r.URL.Query().Get("difficulty"); d != "" {
+		if filter.Difficulty, err = parseDifficulty(d); err != nil {
+			return filter, err
+		}
+	}
+	if t := r.URL.Query().Get("type"); t != "" {
+		if filter.Type, err = parseQuestionType(t); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// Handler to list the question bank of a course to its owner
+// Supports filtering by tag and difficulty
+func (s *Server) getQuestions(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseQuestionFilter(r, courseID)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	questions, err := s.questionStore.ListQuestions(r.Context(), filter, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, questions)
+}
+
+// Handler to add a question to the question bank of a course
+func (s *Server) postQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var question schema.Question
+	if err := json.NewDecoder(r.Body).Decode(&question); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateQuestion(&question); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+	question.ID = 0
+	question.CourseID = courseID
+
+	if err := s.questionStore.CreateQuestion(r.Context(), &question); err != nil {
+		utils.WriteErrorResponse(w, "failed to create question", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, question)
+}
+
+// Handler to edit a question of the bank
+// The course of a question can not be changed, nor the questions used by a quiz
+func (s *Server) putQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing, err := s.questionStore.GetQuestionById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "question")
+		return
+	}
+	if !s.authorizeQuestionOwner(w, r, existing) {
+		return
+	}
+
+	var question schema.Question
+	if err := json.NewDecoder(r.Body).Decode(&question); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateQuestion(&question); err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	question.ID = existing.ID
+	question.CourseID = existing.CourseID
+	question.CreatedAt = existing.CreatedAt
+
+	if err := s.questionStore.UpdateQuestion(r.Context(), &question); err != nil {
+		if errors.Is(err, store.ErrQuestionInUse) {
+			utils.WriteErrorResponse(w, "the question is used by a quiz and can not be changed, add a new question instead", http.StatusConflict)
+			return
+		}
+		utils.WriteErrorResponse(w, "failed to update question", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, question)
+}
+
+// Handler to remove a question from the bank
+// Questions used by a quiz can not be removed
+func (s *Server) deleteQuestion(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	question, err := s.questionStore.GetQuestionById(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err, "question")
+		return
+	}
+	if !s.authorizeQuestionOwner(w, r, question) {
+		return
+	}
+
+	if err := s.questionStore.DeleteQuestion(r.Context(), question); err != nil {
+		if errors.Is(err, store.ErrQuestionInUse) {
+			utils.WriteErrorResponse(w, "the question is used by a quiz and can not be deleted", http.StatusConflict)
+			return
+		}
+		utils.WriteErrorResponse(w, "failed to delete question", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, question)
+}
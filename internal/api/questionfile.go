@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/questionfmt"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// maxImportSize is the largest file accepted by the question import
+const maxImportSize = 5 << 20
+
+// importReport is the outcome of a question import
+type importReport struct {
+	Format    questionfmt.Format      `json:"format"`
+	DryRun    bool                    `json:"dry_run"`
+	Parsed    int                     `json:"parsed"`   // questions found in the file, valid or not
+	Imported  int                     `json:"imported"` // always 0 for a dry run
+	Errors    []questionfmt.LineError `json:"errors"`
+	Questions []schema.Question       `json:"questions"` // the valid questions, with their ids once imported
+}
+
+// Handler to import questions from a GIFT, Aiken, CSV or QTI file into the bank of a course
+// The file is the request body. Nothing is imported if any question of the file is invalid,
+// dry_run=true only reports what would be imported
+func (s *Server) importQuestions(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := questionfmt.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	items, errs, err := questionfmt.Parse(format, http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteErrorResponse(w, fmt.Sprintf("the file must not be larger than %d MB", maxImportSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report := importReport{Format: format, DryRun: r.URL.Query().Get("dry_run") == "true", Parsed: len(items) + len(errs), Questions: []schema.Question{}}
+	for _, item := range items {
+		question := item.Question
+		if err := validateQuestion(&question); err != nil {
+			errs = append(errs, questionfmt.LineError{Line: item.Line, File: item.File, Message: err.Error()})
+			continue
+		}
+		question.CourseID = course.ID
+		report.Questions = append(report.Questions, question)
+	}
+	slices.SortStableFunc(errs, func(a, b questionfmt.LineError) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line))
+	})
+	report.Errors = errs
+
+	if report.Parsed == 0 {
+		utils.WriteErrorResponse(w, "the file holds no questions", http.StatusBadRequest)
+		return
+	}
+	if report.DryRun {
+		utils.WriteJSONResponse(w, report)
+		return
+	}
+	if len(report.Errors) > 0 {
+		utils.WriteErrorDetails(w, "the file has errors, no question was imported", http.StatusBadRequest, report.Errors)
+		return
+	}
+
+	if err := s.questionStore.CreateQuestions(r.Context(), report.Questions); err != nil {
+		utils.WriteErrorResponse(w, "failed to import questions", http.StatusInternalServerError)
+		return
+	}
+	report.Imported = len(report.Questions)
+	utils.WriteJSONResponse(w, report)
+}
+
+// Handler to export the question bank of a course as a GIFT, Aiken, CSV or QTI file
+// Supports the filters of the question list. Questions the format can not hold are left out
+// and their ids listed in the X-Skipped-Questions header
+func (s *Server) exportQuestions(w http.ResponseWriter, r *http.Request) {
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := questionfmt.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseQuestionFilter(r, courseID)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	course, err := s.courseStore.GetCourseById(r.Context(), courseID)
+	if err != nil {
+		writeStoreError(w, err, "course")
+		return
+	}
+	if !s.authorizeCourseOwner(w, r, course) {
+		return
+	}
+
+	questions, err := s.questionStore.ListQuestions(r.Context(), filter, -1, 0)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var exported []schema.Question
+	var skipped []string
+	for i := range questions {
+		if questionfmt.Supports(format, &questions[i]) {
+			exported = append(exported, questions[i])
+		} else {
+			skipped = append(skipped, strconv.FormatUint(uint64(questions[i].ID), 10))
+		}
+	}
+
+	extension, contentType := "txt", "text/plain; charset=utf-8"
+	switch format {
+	case questionfmt.GIFT:
+		extension = "gift"
+	case questionfmt.CSV:
+		extension, contentType = "csv", "text/csv"
+	case questionfmt.QTI:
+		extension, contentType = "zip", "application/zip"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="course-%d-questions.%s"`, courseID, extension))
+	if len(skipped) > 0 {
+		w.Header().Set("X-Skipped-Questions", strings.Join(skipped, ","))
+	}
+	if err := questionfmt.Write(format, w, exported); err != nil {
+		s.log(r).Errorf("Failed to export questions of course %d: %v", courseID, err)
+	}
+}
+
+// Handler to export a quiz as a QTI 2.1 package, with the points of every question
+func (s *Server) exportQuiz(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quiz, ok := s.authorizeQuizOwner(w, r, id)
+	if !ok {
+		return
+	}
+	for _, qq := range quiz.Questions {
+		if !questionfmt.Supports(questionfmt.QTI, &qq.Question) {
+			utils.WriteErrorResponse(w, fmt.Sprintf("question %d can not be exported to QTI", qq.QuestionID), http.StatusConflict)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := questionfmt.WriteQuizQTI(&buf, quiz); err != nil {
+		s.log(r).Errorf("Failed to export quiz %d: %v", quiz.ID, err)
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="quiz-%d-qti.zip"`, quiz.ID))
+	w.Write(buf.Bytes())
+}
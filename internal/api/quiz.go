@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// attemptSummary is the view of an attempt returned by the history endpoints
+type attemptSummary struct {
+	ID          uint       `json:"id"`
+	UserID      uint       `json:"user_id"`
+	UserEmail   string     `json:"user_email"`
+	QuizID      uint       `json:"quiz_id"`
+	CourseID    uint       `json:"course_id"`
+	CourseTitle string     `json:"course_title"`
+	Score       float64    `json:"score"`
+	MaxScore    float64    `json:"max_score"`
+	StartedAt   time.Time  `json:"started_at"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+	Late        bool       `json:"late"`
+	Passed      *bool      `json:"passed"`
+}
+
+func summarizeAttempts(attempts []schema.QuizAttempt) []attemptSummary {
+	summaries := make([]attemptSummary, len(attempts))
+	for i, a := range attempts {
+		summaries[i] = attemptSummary{
+			ID:          a.ID,
+			UserID:      a.UserID,
+			UserEmail:   a.User.Email,
+			QuizID:      a.QuizID,
+			CourseID:    a.Quiz.CourseID,
+			CourseTitle: a.Quiz.Course.Title,
+			Score:       a.Score,
+			MaxScore:    a.MaxScore,
+			StartedAt:   a.StartedAt,
+			SubmittedAt: a.SubmittedAt,
+			Late:        a.Late,
+			Passed:      a.Passed,
+		}
+	}
+	return summaries
+}
+
+// Handler to submit answers for a quiz
+// The answers are graded and stored as an attempt
+func (s *Server) submitQuiz(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		utils.WriteErrorResponse(w, "Invalid Content-Type", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		QuizID    uint               `json:"quiz_id"`
+		Answers   []scoring.Response `json:"answers"`
+		TimeSpent []float64          `json:"time_spent"` // optional, seconds spent on every question
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.QuizID == 0 {
+		utils.WriteErrorResponse(w, "quiz_id is required", http.StatusBadRequest)
+		return
+	}
+
+	quiz, err := s.quizStore.GetQuizById(r.Context(), req.QuizID)
+	if err != nil {
+		writeStoreError(w, err, "quiz")
+		return
+	}
+	if quiz.Adaptive {
+		utils.WriteErrorResponse(w, "adaptive quizzes are answered one question at a time", http.StatusConflict)
+		return
+	}
+	questions := quiz.Questions
+	if len(req.Answers) > len(questions) {
+		utils.WriteErrorResponse(w, "more answers than questions", http.StatusBadRequest)
+		return
+	}
+	if len(req.TimeSpent) > len(questions) {
+		utils.WriteErrorResponse(w, "more time_spent entries than questions", http.StatusBadRequest)
+		return
+	}
+	if slices.ContainsFunc(req.TimeSpent, func(seconds float64) bool { return seconds < 0 }) {
+		utils.WriteErrorResponse(w, "time_spent must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+	if !s.requireEnrollment(w, r, user, quiz.CourseID) {
+		return
+	}
+
+	// Complete the attempt started when the quiz was fetched, if there is one
+	role := roleFromContext(r)
+	settings := attemptSettings(quiz, role)
+	attempt, err := s.quizStore.GetOpenAttempt(r.Context(), user.ID, quiz.ID)
+	if err != nil {
+		utils.WriteErrorResponse(w, "failed to submit quiz", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	if attempt == nil {
+		if attempt, err = s.newAttempt(r.Context(), quiz, settings, user.ID, now); err != nil {
+			writeAttemptError(w, err)
+			return
+		}
+	}
+
+	// Late submissions are handled as the late policy of the quiz says
+	late := pastDeadline(attempt, now)
+	if late && lateRejected(settings) {
+		if err := s.closeExpiredAttempt(r.Context(), quiz, attempt); err != nil {
+			s.log(r).Errorf("Failed to close attempt %d: %v", attempt.ID, err)
+		}
+		writeAttemptError(w, errPastDeadline)
+		return
+	}
+
+	// Answers of a variant are given in the order it was served in
+	if quiz.PerStudentVariants && !canSeeAnswers(role) {
+		attempt.VariantSeed = variantSeed(quiz.Seed, user.ID)
+		questions = quizVariant(questions, attempt.VariantSeed)
+	}
+
+	rules := quiz.Settings.Scoring
+	gradeAttempt(attempt, questions, req.Answers, rules)
+	for i := range req.TimeSpent {
+		attempt.Answers[i].TimeSpent = &req.TimeSpent[i]
+	}
+	attempt.SubmittedAt = &now
+	attempt.Late = late
+	if late && settings.LatePolicy == schema.LatePenalize {
+		attempt.Score *= 1 - settings.LatePenalty
+		attempt.Passed = scoring.Passed(rules, attempt.Score, attempt.MaxScore)
+	}
+
+	if err := s.submitAttempt(r.Context(), quiz, attempt); err != nil {
+		utils.WriteErrorResponse(w, "failed to submit quiz", http.StatusInternalServerError)
+		return
+	}
+	s.log(r).Debugf("User %d scored %.0f/%.0f on quiz %d", user.ID, attempt.Score, attempt.MaxScore, quiz.ID)
+
+	utils.WriteJSONResponse(w, attempt)
+}
+
+// Handler to list the submitted attempts of the current user
+func (s *Server) getQuizHistory(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, err := s.userStore.GetUserFromContext(r.Context())
+	if err != nil {
+		writeCurrentUserError(w, err)
+		return
+	}
+
+	attempts, err := s.quizStore.ListAttempts(r.Context(), store.AttemptFilter{UserID: user.ID}, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, summarizeAttempts(attempts))
+}
+
+// Handler to list the submitted attempts of every user for a course or a quiz
+// Requires at least one of course_id or quiz_id, only the owner of the course can see them
+func (s *Server) getQuizAttempts(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var filter store.AttemptFilter
+	if q := r.URL.Query().Get("course_id"); q != "" {
+		id, err := strconv.Atoi(q)
+		if err != nil {
+			utils.WriteErrorResponse(w, "Invalid course id, must be a number", http.StatusBadRequest)
+			return
+		}
+		filter.CourseID = uint(id)
+	}
+	if q := r.URL.Query().Get("quiz_id"); q != "" {
+		id, err := strconv.Atoi(q)
+		if err != nil {
+			utils.WriteErrorResponse(w, "Invalid quiz id, must be a number", http.StatusBadRequest)
+			return
+		}
+		filter.QuizID = uint(id)
+	}
+	if filter.CourseID == 0 && filter.QuizID == 0 {
+		utils.WriteErrorResponse(w, "course_id or quiz_id is required", http.StatusBadRequest)
+		return
+	}
+	// the attempts of a quiz are all in its course, so the owner of that course is enough
+	if filter.QuizID != 0 {
+		if _, ok := s.authorizeQuizOwner(w, r, filter.QuizID); !ok {
+			return
+		}
+	} else {
+		course, err := s.courseStore.GetCourseById(r.Context(), filter.CourseID)
+		if err != nil {
+			writeStoreError(w, err, "course")
+			return
+		}
+		if !s.authorizeCourseOwner(w, r, course) {
+			return
+		}
+	}
+
+	attempts, err := s.quizStore.ListAttempts(r.Context(), filter, limit, offset)
+	if err != nil {
+		utils.WriteErrorResponse(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteJSONResponse(w, summarizeAttempts(attempts))
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// Quizzes are generated from a recorded seed so they can be regenerated for audits.
+// math/rand is used on purpose, its sources are stable across go versions.
+
+// shuffleOptions shuffles the options of the questions of a new quiz with its seed
+// The order is kept on every quiz question, so the quiz generated again from the seed is the quiz served.
+// The questions keep the order of the bank, the store puts them in the order of the quiz
+func shuffleOptions(questions []schema.QuizQuestion, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	for i := range questions {
+		q := questions[i].Question
+		shuffleQuestion(rng, &q)
+		questions[i].Options, questions[i].Matches = q.Options, q.Matches
+	}
+}
+
+// variantSeed derives the seed of the variant of a quiz served to a user
+func variantSeed(quizSeed int64, userID uint) int64 {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], uint64(quizSeed))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(userID))
+	h := fnv.New64a()
+	h.Write(buf[:])
+	return int64(h.Sum64())
+}
+
+// quizVariant reorders the questions and their options with the seed
+// Every question keeps its position in the original quiz
+func quizVariant(questions []schema.QuizQuestion, seed int64) []schema.QuizQuestion {
+	rng := rand.New(rand.NewSource(seed))
+	variant := make([]schema.QuizQuestion, len(questions))
+	for i, pos := range rng.Perm(len(questions)) {
+		q := questions[pos]
+		shuffleQuestion(rng, &q.Question)
+		variant[i] = q
+	}
+	return variant
+}
+
+// applyVariant returns a copy of the quiz with the questions of the variant for the seed
+func applyVariant(quiz *schema.Quiz, seed int64) *schema.Quiz {
+	served := *quiz
+	served.Questions = quizVariant(quiz.Questions, seed)
+	return &served
+}
+
+func shuffled(rng *rand.Rand, values []string) []string {
+	out := append([]string(nil), values...)
+	rng.Shuffle(len(out), func(a, b int) { out[a], out[b] = out[b], out[a] })
+	return out
+}
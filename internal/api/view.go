@@ -0,0 +1,82 @@
+package api
+
+import (
+	"math/rand"
+	"net/http"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// studentQuestion is a question as shown to students, without the answer
+type studentQuestion struct {
+	ID       uint                `json:"id,omitempty"`
+	Position int                 `json:"position"`
+	Points   float64             `json:"points"`
+	Type     schema.QuestionType `json:"type"`
+	Question string              `json:"question"`
+	Options  []string            `json:"options,omitempty"`
+	Matches  []string            `json:"matches,omitempty"` // the entries to match the options with
+}
+
+// studentQuiz is a quiz as shown to students
+type studentQuiz struct {
+	*schema.Quiz
+	Questions []studentQuestion `json:"questions"`
+}
+
+// roleFromContext returns the role stored by RBACMiddleware
+func roleFromContext(r *http.Request) schema.Role {
+	role, _ := r.Context().Value("userRole").(schema.Role)
+	return role
+}
+
+// canSeeAnswers reports if the role is allowed to see the answers of a quiz
+func canSeeAnswers(role schema.Role) bool {
+	return role == schema.Educator || role == schema.Admin
+}
+
+// projectQuiz returns the quiz as the given role is allowed to see it
+// Students get the questions without answers, optionally with shuffled options
+func projectQuiz(quiz *schema.Quiz, role schema.Role, shuffleOptions bool) any {
+	if canSeeAnswers(role) {
+		return quiz
+	}
+
+	var rng *rand.Rand
+	if shuffleOptions {
+		rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	stripped := make([]studentQuestion, len(quiz.Questions))
+	for i, qq := range quiz.Questions {
+		q := qq.Question
+		if rng != nil {
+			shuffleQuestion(rng, &q)
+		}
+		stripped[i] = studentQuestion{
+			ID:       q.ID,
+			Position: qq.Position,
+			Points:   qq.Points,
+			Type:     scoring.Type(&q),
+			Question: q.Question,
+			Options:  q.Options,
+			Matches:  q.Matches,
+		}
+	}
+
+	if quiz.Adaptive {
+		stripped = []studentQuestion{} // served one at a time by getNextQuestion
+	}
+
+	projected := *quiz
+	projected.Seed = 0 // the seed gives away the order of the other variants
+	return studentQuiz{Quiz: &projected, Questions: stripped}
+}
+
+// writeQuizResponse writes the quiz projected for the role of the current user
+// Every handler returning a quiz should go through this so answers never leak to students
+func (s *Server) writeQuizResponse(w http.ResponseWriter, r *http.Request, quiz *schema.Quiz) {
+	shuffle := r.URL.Query().Get("shuffle_options") == "true"
+	utils.WriteJSONResponse(w, projectQuiz(quiz, roleFromContext(r), shuffle))
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email is already registered")
+)
+
+// UserRecord is the identity returned by a provider after creating a user
+type UserRecord struct {
+	UID         string `json:"uid"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+}
+
+// Authenticator creates identities and verifies the bearer tokens sent by clients.
+// The api server only talks to this interface so the provider can be swapped from config.
+type Authenticator interface {
+	CreateUser(ctx context.Context, email, password string) (*UserRecord, error)
+	// VerifyToken returns the UID of the user the token was issued to
+	VerifyToken(ctx context.Context, token string) (string, error)
+}
+
+// TokenIssuer is implemented by providers which can sign users in themselves.
+// Firebase does not implement it since clients sign in with google directly.
+type TokenIssuer interface {
+	SignIn(ctx context.Context, email, password string) (string, error)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+
+	firebase "firebase.google.com/go"
+	fbauth "firebase.google.com/go/auth"
+	"google.golang.org/api/option"
+)
+
+// Firebase verifies the ID tokens issued by Firebase Authentication
+type Firebase struct {
+	client *fbauth.Client
+}
+
+// NewFirebase initializes the Firebase SDK from a service account key file
+func NewFirebase(ctx context.Context, credentialsPath string) (*Firebase, error) {
+	opt := option.WithCredentialsFile(credentialsPath)
+	app, err := firebase.NewApp(ctx, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Firebase{client: client}, nil
+}
+
+func (f *Firebase) CreateUser(ctx context.Context, email, password string) (*UserRecord, error) {
+	params := (&fbauth.UserToCreate{}).Email(email).Password(password)
+	record, err := f.client.CreateUser(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &UserRecord{
+		UID:         record.UserInfo.UID,
+		Email:       record.UserInfo.Email,
+		DisplayName: record.UserInfo.DisplayName,
+	}, nil
+}
+
+func (f *Firebase) VerifyToken(ctx context.Context, token string) (string, error) {
+	t, err := f.client.VerifyIDToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return t.UID, nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const localIssuer = "rudransh-backend-task"
+
+// LocalCredential stores the password hash of users managed by the local provider
+type LocalCredential struct {
+	UID          string `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// Local is a self contained provider which keeps credentials in our own database
+// and issues HMAC signed JWTs. It is meant for local development and CI.
+type Local struct {
+	db     *gorm.DB
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewLocal(db *gorm.DB, secret string, ttl time.Duration) (*Local, error) {
+	if secret == "" {
+		return nil, errors.New("a secret is required to sign tokens")
+	}
+	if err := db.AutoMigrate(&LocalCredential{}); err != nil {
+		return nil, err
+	}
+	return &Local{db: db, secret: []byte(secret), ttl: ttl}, nil
+}
+
+func (l *Local) CreateUser(ctx context.Context, email, password string) (*UserRecord, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	// fast path sparing the hash, two sign ups at once are told apart by the unique index below
+	var count int64
+	if err := l.db.WithContext(ctx).Model(&LocalCredential{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	cred := LocalCredential{
+		UID:          uuid.NewString(),
+		Email:        email,
+		PasswordHash: string(hash),
+	}
+	if err := l.db.WithContext(ctx).Create(&cred).Error; err != nil {
+		// needs the TranslateError option of gorm, set by db.NewDB
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return &UserRecord{UID: cred.UID, Email: cred.Email}, nil
+}
+
+// SignIn checks the password and returns a signed token for the user
+func (l *Local) SignIn(ctx context.Context, email, password string) (string, error) {
+	var cred LocalCredential
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := l.db.WithContext(ctx).Where("email = ?", email).First(&cred).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrInvalidCredentials
+		}
+		return "", err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password)); err != nil {
+		return "", ErrInvalidCredentials
+	}
+	return l.issue(cred.UID, time.Now())
+}
+
+func (l *Local) issue(uid string, now time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   uid,
+		Issuer:    localIssuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(l.ttl)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(l.secret)
+}
+
+func (l *Local) VerifyToken(ctx context.Context, token string) (string, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return l.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(localIssuer), jwt.WithExpirationRequired())
+	if err != nil || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	l, err := NewLocal(db, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create local provider: %v", err)
+	}
+	return l
+}
+
+func TestLocal_SignInAndVerify(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	record, err := l.CreateUser(ctx, "Test@Example.com", "password")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if record.Email != "test@example.com" {
+		t.Errorf("expected email to be normalized, got %s", record.Email)
+	}
+
+	token, err := l.SignIn(ctx, "test@example.com", "password")
+	if err != nil {
+		t.Fatalf("failed to sign in: %v", err)
+	}
+	uid, err := l.VerifyToken(ctx, token)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+	if uid != record.UID {
+		t.Errorf("expected uid %s, got %s", record.UID, uid)
+	}
+}
+
+func TestLocal_DuplicateEmail(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	if _, err := l.CreateUser(ctx, "test@example.com", "password"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := l.CreateUser(ctx, "test@example.com", "password"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+}
+
+func TestLocal_ConcurrentDuplicateEmail(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	// the count passes for a sign up racing another one, the insert has to catch it
+	if err := l.db.Create(&LocalCredential{UID: "other", Email: "test@example.com", PasswordHash: "hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	l.db.Callback().Query().Before("gorm:query").Register("test:hide_credentials", func(db *gorm.DB) {
+		db.Statement.Where("1 = 0")
+	})
+	if _, err := l.CreateUser(ctx, "test@example.com", "password"); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+}
+
+func TestLocal_WrongPassword(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	if _, err := l.CreateUser(ctx, "test@example.com", "password"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := l.SignIn(ctx, "test@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestLocal_RejectsBadTokens(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	expired, _ := l.issue("uid", time.Now().Add(-2*time.Hour))
+	other := &Local{secret: []byte("other-secret"), ttl: time.Hour}
+	forged, _ := other.issue("uid", time.Now())
+
+	for name, token := range map[string]string{"expired": expired, "forged": forged, "garbage": "not-a-token"} {
+		if _, err := l.VerifyToken(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
@@ -13,6 +13,19 @@ type Config struct {
 	Port             string
 	Mode             string
 	GoogleConfigPath string
+	AuthProvider     string // "firebase" or "local"
+	JWTSecret        string // used to sign tokens when AuthProvider is "local"
+	JWTExpiryMinutes int
+	QuizGenerator    string // generator used when a quiz request names none: "bank", "template" or "llm"
+	LLMURL           string // base URL of an OpenAI compatible API, the llm generator is off when empty
+	LLMAPIKey        string
+	LLMModel         string
+	LLMTimeout       int    // seconds, less than the write timeout of the server
+	ShutdownTimeout  int    // seconds requests in flight are given to complete on shutdown
+	TracesExporter   string // "none", "stdout" or "otlp"
+	ServiceName      string // service.name of the traces
+	LogFormat        string // "text" or "json"
+	LogLevel         string // "debug", "info", "warn" or "error"
 }
 
 var Envs = initConfig()
@@ -24,6 +37,19 @@ func initConfig() Config {
 		Port:             getEnv("PORT", "8080"),
 		Mode:             getEnv("MODE", "development"),
 		GoogleConfigPath: getEnv("GOOGLE_CONFIG_PATH", "key.json"),
+		AuthProvider:     getEnv("AUTH_PROVIDER", "firebase"),
+		JWTSecret:        getEnv("JWT_SECRET", ""),
+		JWTExpiryMinutes: getEnvInt("JWT_EXPIRY_MINUTES", 60),
+		QuizGenerator:    getEnv("QUIZ_GENERATOR", "bank"),
+		LLMURL:           getEnv("LLM_URL", ""),
+		LLMAPIKey:        getEnv("LLM_API_KEY", ""),
+		LLMModel:         getEnv("LLM_MODEL", "gpt-4o-mini"),
+		LLMTimeout:       getEnvInt("LLM_TIMEOUT_SECONDS", 10),
+		ShutdownTimeout:  getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 10),
+		TracesExporter:   getEnv("TRACES_EXPORTER", "none"),
+		ServiceName:      getEnv("OTEL_SERVICE_NAME", "rudransh-backend-task"),
+		LogFormat:        getEnv("LOG_FORMAT", "text"),
+		LogLevel:         getEnv("LOG_LEVEL", "info"),
 	}
 }
 
@@ -1,15 +1,22 @@
 package db
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/glebarez/sqlite"
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
 	"gorm.io/gorm"
 )
 
+// models are the schemas migrated by NewDB
+var models = []any{&schema.User{}, &schema.Course{}, &schema.Module{}, &schema.Lesson{}, &schema.Enrollment{}, &schema.Question{}, &schema.QuestionTag{}, &schema.Quiz{}, &schema.QuizQuestion{}, &schema.QuizAttempt{}, &schema.AttemptAnswer{}}
+
 func NewDB() (*gorm.DB, error) {
 	dbName := "db.sqlite3"
 	database, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{
-		PrepareStmt: true,
+		PrepareStmt:    true,
+		TranslateError: true, // lets the stores tell duplicate keys apart from other failures
 	})
 	if err != nil {
 		return nil, err
@@ -17,11 +24,116 @@ func NewDB() (*gorm.DB, error) {
 	sqlDB, err := database.DB()
 	sqlDB.Exec("PRAGMA foreign_keys = ON")
 
+	// QuizzesTaken grew into QuizAttempt, keep the rows recorded before the rename
+	migrator := database.Migrator()
+	if migrator.HasTable("quizzes_takens") && !migrator.HasTable(&schema.QuizAttempt{}) {
+		if err := migrator.RenameTable("quizzes_takens", &schema.QuizAttempt{}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Answers graded before the points earned were recorded are backfilled after the migration
+	backfillPoints := migrator.HasTable(&schema.AttemptAnswer{}) && !migrator.HasColumn(&schema.AttemptAnswer{}, "Points")
+
 	// Migrate our schemas
-	err = database.AutoMigrate(&schema.User{}, &schema.Course{}, &schema.Quiz{}, &schema.QuizzesTaken{})
+	err = database.AutoMigrate(models...)
 
 	if err != nil {
 		return nil, err
 	}
+
+	// Questions stored before question types existed are single choice
+	err = database.Model(&schema.Question{}).Where("type IS NULL OR type = ''").Update("type", schema.SingleChoice).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateQuizQuestions(database); err != nil {
+		return nil, err
+	}
+	if backfillPoints {
+		err = database.Exec(`UPDATE attempt_answers SET points = CASE WHEN correct THEN COALESCE((
+			SELECT quiz_questions.points FROM quiz_questions
+			JOIN quiz_attempts ON quiz_attempts.quiz_id = quiz_questions.quiz_id
+			WHERE quiz_attempts.id = attempt_answers.attempt_id AND quiz_questions.position = attempt_answers.position
+		), 1) ELSE 0 END`).Error
+		if err != nil {
+			return nil, err
+		}
+	}
 	return database, nil
 }
+
+// Migrated checks every table and column of the schemas exists, naming the first one missing
+func Migrated(database *gorm.DB) error {
+	migrator := database.Migrator()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: database}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table %s is missing", stmt.Schema.Table)
+		}
+		for _, column := range stmt.Schema.DBNames {
+			if !migrator.HasColumn(model, column) {
+				return fmt.Errorf("column %s.%s is missing", stmt.Schema.Table, column)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateQuizQuestions moves the questions of the quizzes out of the JSON column they used to be stored in
+// Every question points to the bank question it was generated from, the ones which are not in the bank
+// anymore (or were generated before the bank existed) are added back to the bank of the course
+func migrateQuizQuestions(database *gorm.DB) error {
+	if !database.Migrator().HasColumn("quizzes", "questions") {
+		return nil
+	}
+	var quizzes []struct {
+		ID        uint
+		CourseID  uint
+		Questions string
+	}
+	if err := database.Table("quizzes").Select("id, course_id, questions").Scan(&quizzes).Error; err != nil {
+		return err
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		for _, quiz := range quizzes {
+			var questions []schema.Question
+			if quiz.Questions != "" {
+				if err := json.Unmarshal([]byte(quiz.Questions), &questions); err != nil {
+					return fmt.Errorf("quiz %d: %w", quiz.ID, err)
+				}
+			}
+			for position, q := range questions {
+				var bank []schema.Question
+				if q.ID != 0 {
+					if err := tx.Where("id = ? AND course_id = ?", q.ID, quiz.CourseID).Limit(1).Find(&bank).Error; err != nil {
+						return err
+					}
+				}
+				if len(bank) == 0 {
+					q.ID, q.CourseID, q.Tags = 0, quiz.CourseID, nil
+					if q.Type == "" {
+						q.Type = schema.SingleChoice
+					}
+					if q.Difficulty == "" {
+						q.Difficulty = schema.Medium
+					}
+					if err := tx.Omit("Course").Create(&q).Error; err != nil {
+						return err
+					}
+					bank = append(bank, q)
+				}
+				link := schema.QuizQuestion{QuizID: quiz.ID, Position: position, QuestionID: bank[0].ID, Points: 1}
+				if err := tx.Omit("Question").Create(&link).Error; err != nil {
+					return err
+				}
+			}
+		}
+		// ALTER TABLE instead of the migrator, which recreates the table and would cascade to the attempts
+		return tx.Exec("ALTER TABLE quizzes DROP COLUMN questions").Error
+	})
+}
@@ -0,0 +1,29 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/store"
+)
+
+// BankGenerator picks the questions at random from the question bank of the course
+type BankGenerator struct {
+	questions store.QuestionStoreInterface
+}
+
+func NewBank(questions store.QuestionStoreInterface) *BankGenerator {
+	return &BankGenerator{questions: questions}
+}
+
+func (g *BankGenerator) Generate(ctx context.Context, req Request) ([]schema.Question, error) {
+	filter := store.QuestionFilter{CourseID: req.CourseID, Tag: req.Tag, Difficulty: req.Difficulty, Type: req.Type}
+	pool, err := g.questions.ListQuestions(ctx, filter, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pool) < req.Number {
+		return nil, &NotEnoughQuestionsError{Requested: req.Number, Available: len(pool), source: "questions in the bank match"}
+	}
+	return pick(pool, req.Number, req.Seed), nil
+}
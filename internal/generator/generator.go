@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// Names of the generators, a quiz request picks one of them
+const (
+	Bank     = "bank"
+	Template = "template"
+	LLM      = "llm"
+)
+
+var (
+	// ErrUnsupported is returned when a generator can not honour an option of the request
+	ErrUnsupported = errors.New("not supported by this generator")
+	// ErrUnavailable is returned when a remote generator fails or answers with garbage
+	ErrUnavailable = errors.New("the quiz generator is unavailable")
+)
+
+// Request is what a generator is asked for
+type Request struct {
+	CourseID   uint
+	Lessons    []schema.Lesson // the content of the course, or of the lesson the quiz is attached to
+	Number     int
+	Tag        string              // optional
+	Difficulty schema.Difficulty   // optional
+	Type       schema.QuestionType // optional
+	Seed       int64               // generators use it to make the same choices again
+}
+
+// QuizGenerator returns the questions of a new quiz
+// Questions with an id are already in the bank of the course, the others are new
+type QuizGenerator interface {
+	Generate(ctx context.Context, req Request) ([]schema.Question, error)
+}
+
+// NotEnoughQuestionsError is returned when fewer questions than requested can be made
+type NotEnoughQuestionsError struct {
+	Requested int
+	Available int
+	source    string
+}
+
+func (e *NotEnoughQuestionsError) Error() string {
+	return fmt.Sprintf("only %d %s", e.Available, e.source)
+}
+
+// pick picks n questions out of the pool
+// The same pool (in the same order) and seed always give the same questions.
+// math/rand is used on purpose, its sources are stable across go versions
+func pick(pool []schema.Question, n int, seed int64) []schema.Question {
+	rng := rand.New(rand.NewSource(seed))
+	picked := make([]schema.Question, 0, n)
+	for _, i := range rng.Perm(len(pool))[:n] {
+		picked = append(picked, pool[i])
+	}
+	return picked
+}
@@ -0,0 +1,167 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+var lessons = []schema.Lesson{
+	{Title: "Concurrency", Body: "# Concurrency\n\n**Goroutine**: a function running concurrently with others.\n- **Channel:** a typed pipe between goroutines\n\nSome text that is not a definition."},
+	{Title: "Tools", Body: "- Go vet: reports suspicious constructs\n- `gofmt`: formats Go source code\n* Goroutine: defined twice"},
+}
+
+func TestExtractFacts(t *testing.T) {
+	want := []fact{
+		{"Goroutine", "a function running concurrently with others"},
+		{"Channel", "a typed pipe between goroutines"},
+		{"Go vet", "reports suspicious constructs"},
+		{"gofmt", "formats Go source code"},
+	}
+	if got := extractFacts(lessons); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestTemplate_SameSeedSameQuestions(t *testing.T) {
+	g := NewTemplate()
+	req := Request{Lessons: lessons, Number: 4, Seed: 7}
+	first, err := g.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := g.Generate(context.Background(), req)
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("expected the same questions for the same seed")
+	}
+
+	// 4 questions about 4 facts ask about every fact once
+	asked := map[string]bool{}
+	for _, q := range first {
+		for _, f := range extractFacts(lessons) {
+			if strings.Contains(q.Question, f.term) || strings.Contains(q.Question, f.definition) {
+				asked[f.term] = true
+			}
+		}
+	}
+	if len(asked) != 4 {
+		t.Errorf("expected a question about every fact, got %v", first)
+	}
+}
+
+func TestTemplate_SharedDefinition(t *testing.T) {
+	shared := []schema.Lesson{{Body: "- Goroutine: a lightweight thread\n- Green thread: A lightweight thread\n- Channel: a typed pipe\n- Mutex: a lock"}}
+	g := NewTemplate()
+	for seed := range int64(20) {
+		questions, err := g.Generate(context.Background(), Request{Lessons: shared, Number: 12, Seed: seed})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, q := range questions {
+			if q.Type == schema.TrueFalse && q.Answer == "false" && strings.Contains(strings.ToLower(q.Question), "lightweight") &&
+				(strings.Contains(q.Question, "Goroutine") || strings.Contains(q.Question, "Green thread")) {
+				t.Errorf("expected the shared definition not to be a false statement, got %+v", q)
+			}
+			for a := range q.Options {
+				for b := range a {
+					if strings.EqualFold(q.Options[a], q.Options[b]) {
+						t.Errorf("expected distinct options, got %+v", q)
+					}
+				}
+			}
+			if q.Type == schema.SingleChoice && strings.Contains(q.Question, "lightweight") {
+				for _, option := range q.Options {
+					if option != q.Answer && (option == "Goroutine" || option == "Green thread") {
+						t.Errorf("expected a single right answer, got %+v", q)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTemplate_Filters(t *testing.T) {
+	g := NewTemplate()
+	questions, err := g.Generate(context.Background(), Request{Lessons: lessons, Number: 4, Type: schema.SingleChoice, Seed: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, q := range questions {
+		if q.Type != schema.SingleChoice || q.Difficulty != schema.Medium || len(q.Options) != 4 {
+			t.Errorf("expected a medium single choice question with 4 options, got %+v", q)
+		}
+	}
+
+	questions, _ = g.Generate(context.Background(), Request{Lessons: lessons, Number: 2, Difficulty: schema.Hard})
+	for _, q := range questions {
+		if q.Type != schema.ShortText || len(q.Answers) != 1 {
+			t.Errorf("expected a short text question, got %+v", q)
+		}
+	}
+
+	// one fact only makes true/false and short text questions, without distractors
+	_, err = g.Generate(context.Background(), Request{Lessons: lessons[:1], Number: 3, Type: schema.TrueFalse})
+	var notEnough *NotEnoughQuestionsError
+	if !errors.As(err, &notEnough) || notEnough.Available != 2 {
+		t.Errorf("expected not enough questions, got %v", err)
+	}
+	if _, err := g.Generate(context.Background(), Request{Lessons: lessons, Number: 1, Tag: "go"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected tag to be unsupported, got %v", err)
+	}
+}
+
+// fakeLLM answers chat completions with the given content
+func fakeLLM(t *testing.T, status int, content string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "tiny" || req.Seed != 3 || len(req.Messages) != 2 {
+			t.Errorf("unexpected body %+v: %v", req, err)
+		}
+		if !strings.Contains(req.Messages[1].Content, "Write 1 questions of type TRUE_FALSE") || !strings.Contains(req.Messages[1].Content, "Goroutine") {
+			t.Errorf("expected the request and the course content in the prompt, got %q", req.Messages[1].Content)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": chatMessage{Role: "assistant", Content: content}}}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLLM_Generate(t *testing.T) {
+	server := fakeLLM(t, http.StatusOK, "```json\n{\"questions\": [{\"type\": \"true_false\", \"question\": \"Goroutines run concurrently\", \"answer\": \"true\", \"id\": 9}]}\n```")
+	g := NewLLM(server.URL+"/v1/", "key", "tiny", time.Second)
+
+	questions, err := g.Generate(context.Background(), Request{Lessons: lessons, Number: 1, Type: schema.TrueFalse, Seed: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(questions) != 1 || questions[0].Type != schema.TrueFalse || questions[0].Answer != "true" || questions[0].ID != 0 {
+		t.Errorf("unexpected questions %+v", questions)
+	}
+}
+
+func TestLLM_Failures(t *testing.T) {
+	req := Request{Lessons: lessons, Number: 1, Type: schema.TrueFalse, Seed: 3}
+	for name, server := range map[string]*httptest.Server{
+		"status":     fakeLLM(t, http.StatusServiceUnavailable, ""),
+		"not json":   fakeLLM(t, http.StatusOK, "Here are your questions!"),
+		"too few":    fakeLLM(t, http.StatusOK, `{"questions": []}`),
+		"wrong type": fakeLLM(t, http.StatusOK, `{"questions": [{"type": "NUMERIC", "question": "2 + 2?", "answer": "4"}]}`),
+	} {
+		g := NewLLM(server.URL+"/v1", "key", "tiny", time.Second)
+		if _, err := g.Generate(context.Background(), req); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("%s: expected the generator to be unavailable, got %v", name, err)
+		}
+	}
+}
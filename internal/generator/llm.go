@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/telemetry"
+)
+
+const (
+	// maxPromptContent caps the course content sent to the model, in bytes
+	maxPromptContent = 12000
+	// maxCompletionSize caps the response of the model
+	maxCompletionSize = 1 << 20
+)
+
+const systemPrompt = `You write quiz questions about the course content given by the user.
+Answer with a JSON object {"questions": [...]} and nothing else. Every question is an object with the fields:
+type: one of SINGLE_CHOICE, MULTI_SELECT, TRUE_FALSE, NUMERIC, SHORT_TEXT, ORDERING, MATCHING
+question: the text of the question
+options: the options of SINGLE_CHOICE, MULTI_SELECT, ORDERING and MATCHING questions
+answer: the correct option of SINGLE_CHOICE, "true" or "false" for TRUE_FALSE, the number of NUMERIC
+answers: the correct options of MULTI_SELECT, the accepted answers of SHORT_TEXT, the options in order for ORDERING, the match of every option for MATCHING
+matches: the entries the options of a MATCHING question are matched with
+tolerance: the accepted distance to the answer of a NUMERIC question
+difficulty: EASY, MEDIUM or HARD
+Only ask about what the content says.`
+
+// LLMGenerator asks a model behind an OpenAI compatible chat completion endpoint to write the questions
+// Any server speaking the protocol works, e.g. a local llama.cpp or Ollama server
+type LLMGenerator struct {
+	url    string // base URL of the API, e.g. http://localhost:11434/v1
+	apiKey string // optional, sent as a bearer token
+	model  string
+	client *http.Client
+}
+
+func NewLLM(url, apiKey, model string, timeout time.Duration) *LLMGenerator {
+	return &LLMGenerator{url: strings.TrimSuffix(url, "/"), apiKey: apiKey, model: model, client: &http.Client{Timeout: timeout, Transport: telemetry.Transport(http.DefaultTransport)}}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	Seed           int64             `json:"seed"`
+	ResponseFormat map[string]string `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (g *LLMGenerator) Generate(ctx context.Context, req Request) ([]schema.Question, error) {
+	if req.Tag != "" {
+		return nil, fmt.Errorf("tag is %w, only the bank generator filters by tag", ErrUnsupported)
+	}
+	if len(req.Lessons) == 0 {
+		return nil, &NotEnoughQuestionsError{Requested: req.Number, source: "questions can be made, the course has no lessons"}
+	}
+
+	body, err := json.Marshal(chatRequest{
+		Model: g.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt(req)},
+		},
+		Temperature:    0.2,
+		Seed:           req.Seed,
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if g.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+	resp, err := g.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: the model answered with status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	var completion chatResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCompletionSize)).Decode(&completion); err != nil || len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("%w: invalid completion", ErrUnavailable)
+	}
+	var out struct {
+		Questions []schema.Question `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(completion.Choices[0].Message.Content)), &out); err != nil {
+		return nil, fmt.Errorf("%w: the model did not answer with questions: %v", ErrUnavailable, err)
+	}
+	if len(out.Questions) < req.Number {
+		return nil, fmt.Errorf("%w: the model wrote %d questions, %d were asked", ErrUnavailable, len(out.Questions), req.Number)
+	}
+
+	questions := out.Questions[:req.Number]
+	for i := range questions {
+		q := &questions[i]
+		q.ID, q.CourseID, q.Tags = 0, 0, nil
+		q.Type = schema.QuestionType(strings.ToUpper(strings.TrimSpace(string(q.Type))))
+		q.Difficulty = schema.Difficulty(strings.ToUpper(strings.TrimSpace(string(q.Difficulty))))
+		if req.Difficulty != "" {
+			q.Difficulty = req.Difficulty
+		}
+		if req.Type != "" && q.Type != req.Type {
+			return nil, fmt.Errorf("%w: the model wrote a %s question, %s was asked", ErrUnavailable, q.Type, req.Type)
+		}
+	}
+	return questions, nil
+}
+
+// userPrompt asks for the questions and gives the content of the course, cut to maxPromptContent
+func userPrompt(req Request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write %d questions", req.Number)
+	if req.Type != "" {
+		fmt.Fprintf(&b, " of type %s", req.Type)
+	}
+	if req.Difficulty != "" {
+		fmt.Fprintf(&b, " of difficulty %s", req.Difficulty)
+	}
+	b.WriteString(" about this course content.\n")
+	var content strings.Builder
+	for _, lesson := range req.Lessons {
+		fmt.Fprintf(&content, "\n# %s\n\n%s\n", lesson.Title, lesson.Body)
+	}
+	text := content.String()
+	if len(text) > maxPromptContent {
+		text = strings.ToValidUTF8(text[:maxPromptContent], "")
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// stripCodeFence removes the ```json fence some models put around their answer
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
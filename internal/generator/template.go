@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// definitions are the lines of a lesson the template generator learns from:
+//
+//	**Term**: meaning
+//	- **Term:** meaning
+//	- Term: meaning
+var definitions = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:[-*+]\s+)?\*\*([^*]+?)\*\*\s*(?::|-|–|—)\s*(.+)$`),
+	regexp.MustCompile(`^(?:[-*+]\s+)?\*\*([^*]+?):\*\*\s*(.+)$`),
+	regexp.MustCompile(`^[-*+]\s+([^:*]+?):\s+(.+)$`),
+}
+
+const (
+	maxTermWords = 6
+	maxOptions   = 4
+)
+
+// fact is a term defined by a lesson
+type fact struct {
+	term       string
+	definition string
+}
+
+// template writes a question about the fact at index i, the other facts are used as distractors
+type template struct {
+	kind       schema.QuestionType
+	difficulty schema.Difficulty
+	minFacts   int // facts with different definitions needed, the one asked about included
+	write      func(rng *rand.Rand, facts []fact, i int) schema.Question
+}
+
+var templates = []template{
+	{schema.TrueFalse, schema.Easy, 1, trueOrFalse},
+	{schema.SingleChoice, schema.Medium, 2, whichDefinition},
+	{schema.SingleChoice, schema.Medium, 2, whichTerm},
+	{schema.ShortText, schema.Hard, 1, nameTheTerm},
+}
+
+// TemplateGenerator writes questions from the definitions found in the lessons of the course
+// It needs no model and always writes the same questions for the same content and seed
+type TemplateGenerator struct{}
+
+func NewTemplate() *TemplateGenerator {
+	return &TemplateGenerator{}
+}
+
+func (g *TemplateGenerator) Generate(ctx context.Context, req Request) ([]schema.Question, error) {
+	if req.Tag != "" {
+		return nil, fmt.Errorf("tag is %w, only the bank generator filters by tag", ErrUnsupported)
+	}
+	facts := extractFacts(req.Lessons)
+
+	// every fact with every template that fits the request is a candidate
+	type candidate struct {
+		template template
+		fact     int
+	}
+	var candidates []candidate
+	for i := range facts {
+		for _, t := range templates {
+			if 1+distractors(facts, i) < t.minFacts || (req.Type != "" && t.kind != req.Type) || (req.Difficulty != "" && t.difficulty != req.Difficulty) {
+				continue
+			}
+			candidates = append(candidates, candidate{t, i})
+		}
+	}
+	if len(candidates) < req.Number {
+		return nil, &NotEnoughQuestionsError{Requested: req.Number, Available: len(candidates), source: "questions can be made from the course content"}
+	}
+
+	rng := rand.New(rand.NewSource(req.Seed))
+	rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	// ask about as many different facts as possible before asking about one twice
+	used := map[int]bool{}
+	var first, again []candidate
+	for _, c := range candidates {
+		if used[c.fact] {
+			again = append(again, c)
+			continue
+		}
+		used[c.fact] = true
+		first = append(first, c)
+	}
+	questions := make([]schema.Question, 0, req.Number)
+	for _, c := range append(first, again...)[:req.Number] {
+		q := c.template.write(rng, facts, c.fact)
+		q.Type, q.Difficulty = c.template.kind, c.template.difficulty
+		questions = append(questions, q)
+	}
+	return questions, nil
+}
+
+// extractFacts finds the definitions of the lessons, in order and without repeating a term
+func extractFacts(lessons []schema.Lesson) []fact {
+	var facts []fact
+	seen := map[string]bool{}
+	for _, lesson := range lessons {
+		for _, line := range strings.Split(lesson.Body, "\n") {
+			line = strings.TrimSpace(line)
+			for _, re := range definitions {
+				m := re.FindStringSubmatch(line)
+				if m == nil {
+					continue
+				}
+				term, definition := plainText(m[1]), strings.TrimSuffix(plainText(m[2]), ".")
+				key := strings.ToLower(term)
+				if term != "" && len(strings.Fields(term)) <= maxTermWords && len(definition) >= 3 && !seen[key] {
+					seen[key] = true
+					facts = append(facts, fact{term, definition})
+				}
+				break
+			}
+		}
+	}
+	return facts
+}
+
+// plainText removes the inline markdown of a fragment
+func plainText(s string) string {
+	return strings.TrimSpace(strings.NewReplacer("**", "", "__", "", "`", "").Replace(s))
+}
+
+// others returns up to n facts defined differently than the fact at index i, picked at random
+// Two terms can share a definition, the other one would be a second right answer
+func others(rng *rand.Rand, facts []fact, i, n int) []fact {
+	var picked []fact
+	for _, j := range rng.Perm(len(facts)) {
+		if !strings.EqualFold(facts[j].definition, facts[i].definition) && len(picked) < n {
+			picked = append(picked, facts[j])
+		}
+	}
+	return picked
+}
+
+// distractors counts the facts defined differently than the fact at index i
+func distractors(facts []fact, i int) int {
+	n := 0
+	for _, f := range facts {
+		if !strings.EqualFold(f.definition, facts[i].definition) {
+			n++
+		}
+	}
+	return n
+}
+
+// distinct removes the options repeating an earlier one, ignoring case
+func distinct(options []string) []string {
+	var kept []string
+	for _, option := range options {
+		if !slices.ContainsFunc(kept, func(k string) bool { return strings.EqualFold(k, option) }) {
+			kept = append(kept, option)
+		}
+	}
+	return kept
+}
+
+func trueOrFalse(rng *rand.Rand, facts []fact, i int) schema.Question {
+	definition, answer := facts[i].definition, "true"
+	if len(facts) > 1 && rng.Intn(2) == 0 {
+		if picked := others(rng, facts, i, 1); len(picked) > 0 {
+			definition, answer = picked[0].definition, "false"
+		}
+	}
+	return schema.Question{Question: fmt.Sprintf("True or false: %s is described as %q", facts[i].term, definition), Answer: answer}
+}
+
+func whichDefinition(rng *rand.Rand, facts []fact, i int) schema.Question {
+	options := []string{facts[i].definition}
+	for _, f := range others(rng, facts, i, maxOptions-1) {
+		options = append(options, f.definition)
+	}
+	options = distinct(options)
+	rng.Shuffle(len(options), func(a, b int) { options[a], options[b] = options[b], options[a] })
+	return schema.Question{Question: fmt.Sprintf("Which of these describes %s?", facts[i].term), Options: options, Answer: facts[i].definition}
+}
+
+func whichTerm(rng *rand.Rand, facts []fact, i int) schema.Question {
+	options := []string{facts[i].term}
+	for _, f := range others(rng, facts, i, maxOptions-1) {
+		options = append(options, f.term)
+	}
+	options = distinct(options)
+	rng.Shuffle(len(options), func(a, b int) { options[a], options[b] = options[b], options[a] })
+	return schema.Question{Question: fmt.Sprintf("Which term is described as %q?", facts[i].definition), Options: options, Answer: facts[i].term}
+}
+
+func nameTheTerm(rng *rand.Rand, facts []fact, i int) schema.Question {
+	return schema.Question{Question: fmt.Sprintf("Name the term described as %q", facts[i].definition), Answers: []string{facts[i].term}}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+	"gorm.io/gorm"
+)
+
+// startKey is where the plugin keeps the start of a query on the statement
+const startKey = "metrics:start"
+
+// gormPlugin times the queries run through GORM and counts their errors
+type gormPlugin struct {
+	metrics *Metrics
+}
+
+// GORMPlugin returns the plugin recording the queries of a database, install it with db.Use
+func (m *Metrics) GORMPlugin() gorm.Plugin {
+	return &gormPlugin{metrics: m}
+}
+
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	return utils.RegisterQueryCallbacks(db, p.Name(), func(operation string) (func(*gorm.DB), func(*gorm.DB)) {
+		return p.before, p.after(operation)
+	})
+}
+
+func (p *gormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *gormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown" // raw queries are not parsed
+		}
+		p.metrics.queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.metrics.queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+)
+
+// unmatched labels the requests which matched no route, so unknown paths do not each get their own series
+const unmatched = "unmatched"
+
+// route returns the path template of the route matched by the mux router, like /api/v1/courses/{id}
+func route(r *http.Request) string {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return unmatched
+	}
+	template, err := current.GetPathTemplate()
+	if err != nil {
+		return unmatched
+	}
+	return template
+}
+
+// Middleware counts and times the requests by the template of the route they matched
+// It goes on a mux router, the route is only known once the router matched one
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &utils.ResponseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		labels := []string{route(r), r.Method, strconv.Itoa(recorder.StatusCode())}
+		m.requests.WithLabelValues(labels...).Inc()
+		m.requestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RateLimited counts a request rejected by the rate limiter
+func (m *Metrics) RateLimited(r *http.Request) {
+	m.rateLimited.WithLabelValues(route(r)).Inc()
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are the prometheus collectors of the server
+// They live on their own registry so every server, and every test, starts from zero
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	rateLimited     *prometheus.CounterVec
+	queryDuration   *prometheus.HistogramVec
+	queryErrors     *prometheus.CounterVec
+
+	quizzesGenerated  *prometheus.CounterVec
+	attemptsSubmitted *prometheus.CounterVec
+	registrations     *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by route template, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_rate_limited_requests_total",
+			Help: "HTTP requests rejected by the rate limiter, by route template.",
+		}, []string{"route"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time taken by database queries, by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "table"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Database queries which failed, by operation and table. Records not found are not errors.",
+		}, []string{"operation", "table"}),
+		quizzesGenerated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quizzes_generated_total",
+			Help: "Quizzes generated, by generator.",
+		}, []string{"generator"}),
+		attemptsSubmitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "quiz_attempts_submitted_total",
+			Help: "Quiz attempts submitted, by kind of quiz and if they were late.",
+		}, []string{"kind", "late"}),
+		registrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_registrations_total",
+			Help: "Users registered, by role.",
+		}, []string{"role"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.rateLimited,
+		m.queryDuration, m.queryErrors,
+		m.quizzesGenerated, m.attemptsSubmitted, m.registrations,
+	)
+	return m
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// QuizGenerated counts a quiz written by the named generator
+func (m *Metrics) QuizGenerated(generator string) {
+	m.quizzesGenerated.WithLabelValues(generator).Inc()
+}
+
+// AttemptSubmitted counts a submitted attempt of a quiz, adaptive or not
+func (m *Metrics) AttemptSubmitted(adaptive, late bool) {
+	kind := "standard"
+	if adaptive {
+		kind = "adaptive"
+	}
+	m.attemptsSubmitted.WithLabelValues(kind, strconv.FormatBool(late)).Inc()
+}
+
+// UserRegistered counts a new user of the role
+func (m *Metrics) UserRegistered(role string) {
+	m.registrations.WithLabelValues(role).Inc()
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// scrape returns the metrics exposed by m
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 OK, got %d", rr.Code)
+	}
+	return rr.Body.String()
+}
+
+func expectMetrics(t *testing.T, exposed string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(exposed, line) {
+			t.Errorf("expected %q in the metrics", line)
+		}
+	}
+}
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	m := New()
+	r := mux.NewRouter()
+	r.NotFoundHandler = m.Middleware(http.NotFoundHandler())
+	r.Use(m.Middleware)
+	r.HandleFunc("/courses/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "0" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}).Methods("GET")
+
+	for _, path := range []string{"/courses/1", "/courses/2", "/courses/0", "/nowhere/1", "/nowhere/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	exposed := scrape(t, m)
+	expectMetrics(t, exposed,
+		`http_requests_total{method="GET",route="/courses/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/courses/{id}",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/courses/{id}",status="200"} 2`,
+	)
+	if strings.Contains(exposed, "/courses/1") || strings.Contains(exposed, "/nowhere") {
+		t.Error("expected no series labelled with a path")
+	}
+}
+
+func TestGORMPlugin(t *testing.T) {
+	type note struct {
+		ID   uint
+		Text string `gorm:"unique"`
+	}
+	t.Chdir(t.TempDir())
+	db, err := gorm.Open(sqlite.Open("metrics.sqlite3"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&note{}); err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	if err := db.Use(m.GORMPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Create(&note{Text: "first"})
+	if err := db.Create(&note{Text: "first"}).Error; err == nil {
+		t.Fatal("expected the duplicate to fail")
+	}
+	var found note
+	db.First(&found)
+	if err := db.First(&found, 42).Error; err == nil {
+		t.Fatal("expected no record")
+	}
+	expectMetrics(t, scrape(t, m),
+		`db_query_duration_seconds_count{operation="create",table="notes"} 2`,
+		`db_query_duration_seconds_count{operation="query",table="notes"} 2`,
+		`db_query_errors_total{operation="create",table="notes"} 1`,
+	)
+	if strings.Contains(scrape(t, m), `db_query_errors_total{operation="query"`) {
+		t.Error("expected a record not found not to count as an error")
+	}
+}
+
+func TestDomainCounters(t *testing.T) {
+	m := New()
+	m.QuizGenerated("bank")
+	m.QuizGenerated("bank")
+	m.AttemptSubmitted(true, false)
+	m.UserRegistered("student")
+	expectMetrics(t, scrape(t, m),
+		`quizzes_generated_total{generator="bank"} 2`,
+		`quiz_attempts_submitted_total{kind="adaptive",late="false"} 1`,
+		`user_registrations_total{role="student"} 1`,
+	)
+}
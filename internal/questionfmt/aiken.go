@@ -0,0 +1,124 @@
+package questionfmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+)
+
+var (
+	aikenOption = regexp.MustCompile(`^([A-Z])[.)]\s+(.*)$`)
+	aikenAnswer = regexp.MustCompile(`(?i)^ANSWER:\s*([A-Z])$`)
+)
+
+// aikenQuestion is the question being read, its options are lettered from A
+type aikenQuestion struct {
+	line    int
+	text    []string
+	options []string
+}
+
+// parseAiken reads single choice questions: the question, its options lettered A. or A) and an ANSWER: line
+// After an error the rest of the question, up to the next blank line, is skipped
+func parseAiken(r io.Reader) ([]Item, []LineError, error) {
+	items, errs := []Item{}, []LineError{}
+	var current *aikenQuestion
+	skipping := false
+	fail := func(line int, format string, args ...any) {
+		errs = append(errs, LineError{Line: line, Message: fmt.Sprintf(format, args...)})
+		current, skipping = nil, true
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			if current != nil {
+				fail(current.line, "missing ANSWER line")
+			}
+			skipping = false
+			continue
+		}
+		if skipping {
+			continue
+		}
+
+		if m := aikenAnswer.FindStringSubmatch(line); m != nil {
+			if current == nil || len(current.options) == 0 {
+				fail(n, "ANSWER line without options")
+				continue
+			}
+			i := int(strings.ToUpper(m[1])[0] - 'A')
+			if i >= len(current.options) {
+				fail(n, "the answer %s is not one of the options", strings.ToUpper(m[1]))
+				continue
+			}
+			items = append(items, Item{Line: current.line, Question: schema.Question{
+				Type:     schema.SingleChoice,
+				Question: strings.Join(current.text, " "),
+				Options:  current.options,
+				Answer:   current.options[i],
+			}})
+			current = nil
+			continue
+		}
+
+		m := aikenOption.FindStringSubmatch(line)
+		switch {
+		case current == nil:
+			current = &aikenQuestion{line: n, text: []string{line}}
+		case m != nil:
+			if want := byte('A' + len(current.options)); m[1][0] != want {
+				fail(n, "expected option %c, got %s", want, m[1])
+				continue
+			}
+			current.options = append(current.options, strings.TrimSpace(m[2]))
+		case len(current.options) == 0:
+			current.text = append(current.text, line)
+		default:
+			fail(current.line, "missing ANSWER line")
+		}
+	}
+	if current != nil {
+		errs = append(errs, LineError{Line: current.line, Message: "missing ANSWER line"})
+	}
+	return items, errs, scanner.Err()
+}
+
+// Aiken only has single choice questions, with at most 26 options
+func supportsAiken(q *schema.Question) bool {
+	return scoring.Type(q) == schema.SingleChoice && len(q.Options) <= 26
+}
+
+func writeAiken(w io.Writer, questions []schema.Question) error {
+	out := bufio.NewWriter(w)
+	for i, q := range questions {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(aikenLine(q.Question) + "\n")
+		answer := 'A'
+		for j, option := range q.Options {
+			fmt.Fprintf(out, "%c. %s\n", 'A'+j, aikenLine(option))
+			if option == q.Answer {
+				answer = 'A' + rune(j)
+			}
+		}
+		fmt.Fprintf(out, "ANSWER: %c\n", answer)
+	}
+	return out.Flush()
+}
+
+// aikenLine puts text on a single line, a line break would end the question
+func aikenLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,139 @@
+package questionfmt
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// csvColumns are the columns of a CSV question bank, the first row names them in any order
+// Only question is required, lists are separated by |
+var csvColumns = []string{"type", "question", "options", "answer", "answers", "matches", "tolerance", "pattern", "difficulty", "tags"}
+
+const csvListSeparator = "|"
+
+// parseCSV reads one question per row, the values are checked by the question bank
+func parseCSV(r io.Reader) ([]Item, []LineError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return []Item{}, []LineError{}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	column := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			return []Item{}, []LineError{{Line: 1, Message: fmt.Sprintf("unknown column %q, columns are %s", name, strings.Join(csvColumns, ", "))}}, nil
+		}
+		column[name] = i
+	}
+	if _, found := column["question"]; !found {
+		return []Item{}, []LineError{{Line: 1, Message: "the question column is required"}}, nil
+	}
+
+	items, errs := []Item{}, []LineError{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			errs = append(errs, LineError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		get := func(name string) string {
+			if i, found := column[name]; found {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		q := schema.Question{
+			Type:       schema.QuestionType(strings.ToUpper(get("type"))),
+			Question:   get("question"),
+			Options:    splitList(get("options")),
+			Answer:     get("answer"),
+			Answers:    splitList(get("answers")),
+			Matches:    splitList(get("matches")),
+			Pattern:    get("pattern"),
+			Difficulty: schema.Difficulty(strings.ToUpper(get("difficulty"))),
+		}
+		if tolerance := get("tolerance"); tolerance != "" {
+			if q.Tolerance, err = strconv.ParseFloat(tolerance, 64); err != nil {
+				errs = append(errs, LineError{Line: line, Message: "tolerance must be a number"})
+				continue
+			}
+		}
+		for _, tag := range splitList(get("tags")) {
+			q.Tags = append(q.Tags, schema.QuestionTag{Name: tag})
+		}
+		items = append(items, Item{Line: line, Question: q})
+	}
+	return items, errs, nil
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	values := strings.Split(value, csvListSeparator)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// Every question fits in a row, unless one of its list values holds the separator
+func supportsCSV(q *schema.Question) bool {
+	lists := slices.Concat(q.Options, q.Answers, q.Matches)
+	for _, tag := range q.Tags {
+		lists = append(lists, tag.Name)
+	}
+	return !slices.ContainsFunc(lists, func(v string) bool { return strings.Contains(v, csvListSeparator) })
+}
+
+func writeCSV(w io.Writer, questions []schema.Question) error {
+	out := csv.NewWriter(w)
+	out.Write(csvColumns)
+	for _, q := range questions {
+		tags := make([]string, len(q.Tags))
+		for i, tag := range q.Tags {
+			tags[i] = tag.Name
+		}
+		tolerance := ""
+		if q.Tolerance != 0 {
+			tolerance = strconv.FormatFloat(q.Tolerance, 'f', -1, 64)
+		}
+		out.Write([]string{
+			string(q.Type),
+			q.Question,
+			strings.Join(q.Options, csvListSeparator),
+			q.Answer,
+			strings.Join(q.Answers, csvListSeparator),
+			strings.Join(q.Matches, csvListSeparator),
+			tolerance,
+			q.Pattern,
+			string(q.Difficulty),
+			strings.Join(tags, csvListSeparator),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
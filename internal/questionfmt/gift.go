@@ -0,0 +1,462 @@
+package questionfmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+)
+
+// Characters with a meaning in GIFT, they are escaped with a backslash in text
+const giftSpecial = `~=#{}:\`
+
+// Moodle reads [tag:name] from the comments above a question, [difficulty:level] is our own
+var giftMeta = regexp.MustCompile(`\[(tag|difficulty):([^\]]+)\]`)
+
+// giftBlock is a question of a GIFT file, questions are separated by blank lines
+type giftBlock struct {
+	line     int
+	text     string
+	comments []string
+}
+
+// parseGIFT reads the subset of GIFT the question bank can hold: multiple choice, true/false,
+// short answer, numerical and matching questions. Essays and descriptions are reported as errors
+func parseGIFT(r io.Reader) ([]Item, []LineError, error) {
+	blocks, err := giftBlocks(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, errs := []Item{}, []LineError{}
+	for _, block := range blocks {
+		q, err := parseGIFTQuestion(block)
+		if err != nil {
+			errs = append(errs, LineError{Line: block.line, Message: err.Error()})
+			continue
+		}
+		items = append(items, Item{Line: block.line, Question: *q})
+	}
+	return items, errs, nil
+}
+
+func giftBlocks(r io.Reader) ([]giftBlock, error) {
+	var blocks []giftBlock
+	var current *giftBlock
+	var comments []string
+	flush := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"):
+			// comments belong to the question below them
+			if current == nil {
+				comments = append(comments, trimmed)
+			}
+		case strings.HasPrefix(trimmed, "$CATEGORY:"):
+			// categories are a Moodle concept, the bank uses tags
+		case current == nil:
+			current = &giftBlock{line: n, text: line, comments: comments}
+			comments = nil
+		default:
+			current.text += "\n" + line
+		}
+	}
+	flush()
+	return blocks, scanner.Err()
+}
+
+func parseGIFTQuestion(block giftBlock) (*schema.Question, error) {
+	text := strings.TrimSpace(block.text)
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return nil, errors.New("the title is not closed with ::")
+		}
+		text = strings.TrimSpace(text[2+end+2:])
+	}
+
+	open := indexUnescaped(text, "{")
+	if open < 0 {
+		return nil, errors.New("missing answer block, answers go between { and }")
+	}
+	closing := indexUnescaped(text[open:], "}")
+	if closing < 0 {
+		return nil, errors.New("the answer block is not closed with }")
+	}
+	closing += open
+	body := strings.TrimSpace(text[open+1 : closing])
+	stem := strings.TrimSpace(text[:open])
+	if after := strings.TrimSpace(text[closing+1:]); after != "" {
+		// the answer block is a blank in the middle of the sentence
+		stem += " _____ " + after
+	}
+	stem = strings.TrimSpace(giftFormatMarker.ReplaceAllString(stem, ""))
+
+	q, err := parseGIFTAnswer(body)
+	if err != nil {
+		return nil, err
+	}
+	q.Question = unescapeGIFT(stem)
+	for _, comment := range block.comments {
+		for _, m := range giftMeta.FindAllStringSubmatch(comment, -1) {
+			if m[1] == "tag" {
+				q.Tags = append(q.Tags, schema.QuestionTag{Name: strings.TrimSpace(m[2])})
+			} else {
+				q.Difficulty = schema.Difficulty(strings.ToUpper(strings.TrimSpace(m[2])))
+			}
+		}
+	}
+	return q, nil
+}
+
+// Question text can start with the markup it is written in, the bank only holds plain text
+var giftFormatMarker = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+
+// giftAnswer is an answer of a GIFT answer block, like =right or ~%50%partly right
+type giftAnswer struct {
+	correct bool // = answers
+	weight  *float64
+	text    string // still escaped, the sides of a matching pair are split first
+}
+
+func (a giftAnswer) accepted() bool {
+	if a.weight != nil {
+		return *a.weight > 0
+	}
+	return a.correct
+}
+
+func parseGIFTAnswer(body string) (*schema.Question, error) {
+	if strings.HasPrefix(body, "#") {
+		return parseGIFTNumeric(body[1:])
+	}
+	switch strings.ToUpper(cutFeedback(body)) {
+	case "T", "TRUE":
+		return &schema.Question{Type: schema.TrueFalse, Answer: "true"}, nil
+	case "F", "FALSE":
+		return &schema.Question{Type: schema.TrueFalse, Answer: "false"}, nil
+	case "":
+		return nil, errors.New("essay questions are not supported")
+	}
+
+	answers, err := splitGIFTAnswers(body)
+	if err != nil {
+		return nil, err
+	}
+	wrong := slices.ContainsFunc(answers, func(a giftAnswer) bool { return !a.correct })
+	matching := slices.ContainsFunc(answers, func(a giftAnswer) bool { return a.correct && strings.Contains(a.text, "->") })
+
+	q := &schema.Question{}
+	switch {
+	case matching:
+		q.Type = schema.Matching
+		for _, a := range answers {
+			left, right, found := strings.Cut(a.text, "->")
+			if !a.correct || !found {
+				return nil, errors.New("every answer of a matching question must be =option -> match")
+			}
+			left, right = unescapeGIFT(strings.TrimSpace(left)), unescapeGIFT(strings.TrimSpace(right))
+			if !slices.Contains(q.Matches, right) {
+				q.Matches = append(q.Matches, right)
+			}
+			if left != "" {
+				// a pair without an option only adds a wrong match
+				q.Options = append(q.Options, left)
+				q.Answers = append(q.Answers, right)
+			}
+		}
+
+	case wrong:
+		hasWeights := false
+		for _, a := range answers {
+			text := unescapeGIFT(a.text)
+			q.Options = append(q.Options, text)
+			if a.accepted() {
+				q.Answers = append(q.Answers, text)
+			}
+			hasWeights = hasWeights || (!a.correct && a.weight != nil)
+		}
+		if len(q.Answers) == 0 {
+			return nil, errors.New("no correct answer, mark it with = or a positive weight")
+		}
+		q.Type = schema.MultiSelect
+		if len(q.Answers) == 1 && !hasWeights {
+			q.Type, q.Answer, q.Answers = schema.SingleChoice, q.Answers[0], nil
+		}
+
+	default:
+		q.Type = schema.ShortText
+		for _, a := range answers {
+			if a.accepted() {
+				q.Answers = append(q.Answers, unescapeGIFT(a.text))
+			}
+		}
+	}
+	return q, nil
+}
+
+// splitGIFTAnswers splits the answer block on the = and ~ starting every answer
+func splitGIFTAnswers(body string) ([]giftAnswer, error) {
+	var answers []giftAnswer
+	start := -1
+	add := func(end int) error {
+		if start < 0 {
+			if strings.TrimSpace(body[:end]) != "" {
+				return errors.New("answers must start with = or ~")
+			}
+			return nil
+		}
+		a, err := parseGIFTWeight(giftAnswer{correct: body[start] == '='}, body[start+1:end])
+		if err != nil {
+			return err
+		}
+		answers = append(answers, a)
+		return nil
+	}
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			if err := add(i); err != nil {
+				return nil, err
+			}
+			start = i
+		}
+	}
+	if err := add(len(body)); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// parseGIFTWeight reads the optional %weight% before the text of an answer and drops its #feedback
+func parseGIFTWeight(a giftAnswer, text string) (giftAnswer, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "%") {
+		end := strings.Index(text[1:], "%")
+		if end < 0 {
+			return a, errors.New("the weight of an answer is not closed with %")
+		}
+		weight, err := strconv.ParseFloat(text[1:1+end], 64)
+		if err != nil {
+			return a, fmt.Errorf("invalid weight %q", text[1:1+end])
+		}
+		a.weight = &weight
+		text = text[1+end+1:]
+	}
+	text = strings.TrimSpace(cutFeedback(text))
+	if text == "" && !a.correct {
+		return a, errors.New("an answer is empty")
+	}
+	a.text = text
+	return a, nil
+}
+
+// parseGIFTNumeric reads {#answer:tolerance}, {#min..max} or a list of =answers, keeping the first fully correct one
+func parseGIFTNumeric(body string) (*schema.Question, error) {
+	value := strings.TrimSpace(body)
+	if strings.HasPrefix(value, "=") {
+		answers, err := splitGIFTAnswers(value)
+		if err != nil {
+			return nil, err
+		}
+		value = ""
+		for _, a := range answers {
+			if a.correct && (a.weight == nil || *a.weight == 100) {
+				value = a.text
+				break
+			}
+		}
+	}
+	value = strings.TrimSpace(unescapeGIFT(cutFeedback(value)))
+
+	invalid := fmt.Errorf("invalid numerical answer %q", value)
+	q := &schema.Question{Type: schema.Numeric}
+	if low, high, found := strings.Cut(value, ".."); found {
+		lo, err1 := strconv.ParseFloat(strings.TrimSpace(low), 64)
+		hi, err2 := strconv.ParseFloat(strings.TrimSpace(high), 64)
+		if err1 != nil || err2 != nil || hi < lo {
+			return nil, invalid
+		}
+		q.Answer = strconv.FormatFloat((lo+hi)/2, 'f', -1, 64)
+		q.Tolerance = (hi - lo) / 2
+		return q, nil
+	}
+	answer, tolerance, _ := strings.Cut(value, ":")
+	if _, err := strconv.ParseFloat(strings.TrimSpace(answer), 64); err != nil {
+		return nil, invalid
+	}
+	q.Answer = strings.TrimSpace(answer)
+	if tolerance != "" {
+		t, err := strconv.ParseFloat(strings.TrimSpace(tolerance), 64)
+		if err != nil || t < 0 {
+			return nil, invalid
+		}
+		q.Tolerance = t
+	}
+	return q, nil
+}
+
+// cutFeedback drops the #feedback following an answer
+func cutFeedback(text string) string {
+	if i := indexUnescaped(text, "#"); i >= 0 {
+		return strings.TrimSpace(text[:i])
+	}
+	return strings.TrimSpace(text)
+}
+
+// indexUnescaped returns the index of the first sub of s not escaped with a backslash, -1 if there is none
+func indexUnescaped(s, sub string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeGIFT(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch next := s[i+1]; {
+			case next == 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case strings.IndexByte(giftSpecial, next) >= 0:
+				b.WriteByte(next)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func escapeGIFT(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+		case strings.ContainsRune(giftSpecial, c):
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Ordering questions and short text patterns have no GIFT equivalent
+func supportsGIFT(q *schema.Question) bool {
+	switch scoring.Type(q) {
+	case schema.Ordering:
+		return false
+	case schema.ShortText:
+		return q.Pattern == "" && len(q.Answers) > 0
+	case schema.Matching:
+		// the sides of a pair are split on -> which can not be escaped
+		return !slices.ContainsFunc(append(slices.Clone(q.Options), q.Matches...), func(s string) bool { return strings.Contains(s, "->") })
+	}
+	return true
+}
+
+func writeGIFT(w io.Writer, questions []schema.Question) error {
+	out := bufio.NewWriter(w)
+	for i := range questions {
+		q := &questions[i]
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		var meta []string
+		for _, tag := range q.Tags {
+			meta = append(meta, "[tag:"+tag.Name+"]")
+		}
+		if q.Difficulty != "" {
+			meta = append(meta, "[difficulty:"+string(q.Difficulty)+"]")
+		}
+		if len(meta) > 0 {
+			fmt.Fprintf(out, "// %s\n", strings.Join(meta, " "))
+		}
+		fmt.Fprintf(out, "%s {%s}\n", escapeGIFT(q.Question), giftAnswerBlock(q))
+	}
+	return out.Flush()
+}
+
+func giftAnswerBlock(q *schema.Question) string {
+	var b strings.Builder
+	switch scoring.Type(q) {
+	case schema.TrueFalse:
+		return strings.ToUpper(strings.TrimSpace(q.Answer))
+	case schema.Numeric:
+		if q.Tolerance == 0 {
+			return "#" + q.Answer
+		}
+		return "#" + q.Answer + ":" + strconv.FormatFloat(q.Tolerance, 'f', -1, 64)
+	case schema.SingleChoice:
+		for _, option := range q.Options {
+			marker := "~"
+			if option == q.Answer {
+				marker = "="
+			}
+			b.WriteString("\n\t" + marker + escapeGIFT(option))
+		}
+	case schema.MultiSelect:
+		// the correct options share the credit, picking a wrong one loses it all
+		weight := strconv.FormatFloat(100/float64(len(q.Answers)), 'f', 5, 64)
+		weight = strings.TrimRight(strings.TrimRight(weight, "0"), ".")
+		for _, option := range q.Options {
+			if slices.Contains(q.Answers, option) {
+				b.WriteString("\n\t~%" + weight + "%" + escapeGIFT(option))
+			} else {
+				b.WriteString("\n\t~%-100%" + escapeGIFT(option))
+			}
+		}
+	case schema.ShortText:
+		for _, answer := range q.Answers {
+			b.WriteString("\n\t=" + escapeGIFT(answer))
+		}
+	case schema.Matching:
+		for i, option := range q.Options {
+			b.WriteString("\n\t=" + escapeGIFT(option) + " -> " + escapeGIFT(q.Answers[i]))
+		}
+		for _, match := range q.Matches {
+			if !slices.Contains(q.Answers, match) {
+				b.WriteString("\n\t= -> " + escapeGIFT(match))
+			}
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
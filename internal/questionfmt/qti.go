@@ -0,0 +1,681 @@
+package questionfmt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/scoring"
+)
+
+const (
+	qtiNamespace      = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	manifestNamespace = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	qtiMatchCorrect   = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	qtiMapResponse    = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"
+	qtiItemType       = "imsqti_item_xmlv2p1"
+	qtiTestType       = "imsqti_test_xmlv2p1"
+	qtiManifest       = "imsmanifest.xml"
+	qtiResponse       = "RESPONSE"
+)
+
+// qtiMaxFileSize is the largest file of a package which is read, it keeps zip bombs out
+const qtiMaxFileSize = 1 << 20
+
+// qtiMaxItems is the most items a package can have, every one of them is inflated
+const qtiMaxItems = 1000
+
+// qtiItem is an assessmentItem, a question of a QTI package
+type qtiItem struct {
+	XMLName       xml.Name                 `xml:"assessmentItem"`
+	Xmlns         string                   `xml:"xmlns,attr,omitempty"`
+	Identifier    string                   `xml:"identifier,attr"`
+	Title         string                   `xml:"title,attr"`
+	Adaptive      bool                     `xml:"adaptive,attr"`
+	TimeDependent bool                     `xml:"timeDependent,attr"`
+	Responses     []qtiResponseDeclaration `xml:"responseDeclaration"`
+	Outcomes      []qtiOutcomeDeclaration  `xml:"outcomeDeclaration"`
+	Body          qtiMarkup                `xml:"itemBody"`
+	Processing    *qtiResponseProcessing   `xml:"responseProcessing"`
+}
+
+type qtiResponseDeclaration struct {
+	Identifier  string      `xml:"identifier,attr"`
+	Cardinality string      `xml:"cardinality,attr"`
+	BaseType    string      `xml:"baseType,attr"`
+	Correct     []string    `xml:"correctResponse>value"`
+	Mapping     *qtiMapping `xml:"mapping"`
+}
+
+type qtiMapping struct {
+	DefaultValue float64       `xml:"defaultValue,attr"`
+	UpperBound   float64       `xml:"upperBound,attr,omitempty"`
+	Entries      []qtiMapEntry `xml:"mapEntry"`
+}
+
+type qtiMapEntry struct {
+	Key           string  `xml:"mapKey,attr"`
+	Value         float64 `xml:"mappedValue,attr"`
+	CaseSensitive bool    `xml:"caseSensitive,attr"`
+}
+
+type qtiOutcomeDeclaration struct {
+	Identifier  string `xml:"identifier,attr"`
+	Cardinality string `xml:"cardinality,attr"`
+	BaseType    string `xml:"baseType,attr"`
+}
+
+type qtiResponseProcessing struct {
+	Template string `xml:"template,attr,omitempty"`
+	Inner    string `xml:",innerxml"`
+}
+
+// qtiMarkup is XHTML content, kept as is and only turned into text when read
+type qtiMarkup struct {
+	Inner string `xml:",innerxml"`
+}
+
+// qtiInteraction is the part of an item the student answers with
+// XMLName tells the interactions apart, they share the fields they have in common
+type qtiInteraction struct {
+	XMLName            xml.Name
+	ResponseIdentifier string        `xml:"responseIdentifier,attr"`
+	Shuffle            string        `xml:"shuffle,attr,omitempty"`
+	MaxChoices         *int          `xml:"maxChoices,attr"`
+	MaxAssociations    *int          `xml:"maxAssociations,attr"`
+	Prompt             *qtiMarkup    `xml:"prompt"`
+	Choices            []qtiChoice   `xml:"simpleChoice"`
+	MatchSets          []qtiMatchSet `xml:"simpleMatchSet"`
+}
+
+type qtiChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	MatchMax   *int   `xml:"matchMax,attr"`
+	Inner      string `xml:",innerxml"`
+}
+
+type qtiMatchSet struct {
+	Choices []qtiChoice `xml:"simpleAssociableChoice"`
+}
+
+// qtiTest is an assessmentTest, it lists the items of a quiz with their weights
+type qtiTest struct {
+	XMLName    xml.Name       `xml:"assessmentTest"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	Identifier string         `xml:"identifier,attr"`
+	Title      string         `xml:"title,attr"`
+	TimeLimits *qtiTimeLimits `xml:"timeLimits"`
+	Part       struct {
+		Identifier     string `xml:"identifier,attr"`
+		NavigationMode string `xml:"navigationMode,attr"`
+		SubmissionMode string `xml:"submissionMode,attr"`
+		Section        struct {
+			Identifier string       `xml:"identifier,attr"`
+			Title      string       `xml:"title,attr"`
+			Visible    bool         `xml:"visible,attr"`
+			Ordering   *qtiOrdering `xml:"ordering"`
+			Items      []qtiItemRef `xml:"assessmentItemRef"`
+		} `xml:"assessmentSection"`
+	} `xml:"testPart"`
+}
+
+type qtiTimeLimits struct {
+	MaxTime int `xml:"maxTime,attr"` // seconds
+}
+
+type qtiOrdering struct {
+	Shuffle bool `xml:"shuffle,attr"`
+}
+
+type qtiItemRef struct {
+	Identifier string     `xml:"identifier,attr"`
+	Href       string     `xml:"href,attr"`
+	Weight     *qtiWeight `xml:"weight"`
+}
+
+type qtiWeight struct {
+	Identifier string  `xml:"identifier,attr"`
+	Value      float64 `xml:"value,attr"`
+}
+
+// qtiManifestFile is the imsmanifest.xml listing the resources of a package
+type qtiManifestFile struct {
+	XMLName       xml.Name      `xml:"manifest"`
+	Xmlns         string        `xml:"xmlns,attr,omitempty"`
+	Identifier    string        `xml:"identifier,attr"`
+	Metadata      *qtiMetadata  `xml:"metadata"`
+	Organizations struct{}      `xml:"organizations"`
+	Resources     []qtiResource `xml:"resources>resource"`
+}
+
+type qtiMetadata struct {
+	Schema        string `xml:"schema"`
+	SchemaVersion string `xml:"schemaversion"`
+}
+
+type qtiResource struct {
+	Identifier   string          `xml:"identifier,attr"`
+	Type         string          `xml:"type,attr"`
+	Href         string          `xml:"href,attr"`
+	Files        []qtiFile       `xml:"file"`
+	Dependencies []qtiDependency `xml:"dependency"`
+}
+
+type qtiFile struct {
+	Href string `xml:"href,attr"`
+}
+
+type qtiDependency struct {
+	IdentifierRef string `xml:"identifierref,attr"`
+}
+
+// parseQTI reads the items of a QTI 2.x package into questions, the test of the package is ignored
+// Items are reported by the file they are in
+func parseQTI(r io.Reader) ([]Item, []LineError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, errs := []Item{}, []LineError{}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return items, append(errs, LineError{Message: "the file is not a zip package"}), nil
+	}
+
+	var manifest qtiManifestFile
+	if err := readZipXML(archive, qtiManifest, &manifest); err != nil {
+		return items, append(errs, LineError{File: qtiManifest, Message: err.Error()}), nil
+	}
+	// a file listed twice would be inflated twice, it is reported once and read once
+	var names []string
+	listed := map[string]bool{}
+	for _, resource := range manifest.Resources {
+		if !strings.HasPrefix(resource.Type, "imsqti_item_xmlv2p") {
+			continue
+		}
+		name, err := url.PathUnescape(resource.Href)
+		if err != nil {
+			name = resource.Href
+		}
+		name = path.Clean(name)
+		if listed[name] {
+			errs = append(errs, LineError{File: name, Message: "the item is listed more than once in the manifest"})
+			continue
+		}
+		listed[name] = true
+		names = append(names, name)
+	}
+	if len(names) > qtiMaxItems {
+		return items, append(errs, LineError{File: qtiManifest, Message: fmt.Sprintf("the package has more than %d items", qtiMaxItems)}), nil
+	}
+
+	for _, name := range names {
+		var item qtiItem
+		if err := readZipXML(archive, name, &item); err != nil {
+			errs = append(errs, LineError{File: name, Message: err.Error()})
+			continue
+		}
+		q, err := item.question()
+		if err != nil {
+			errs = append(errs, LineError{File: name, Message: err.Error()})
+			continue
+		}
+		items = append(items, Item{File: name, Question: *q})
+	}
+	if len(items) == 0 && len(errs) == 0 {
+		errs = append(errs, LineError{File: qtiManifest, Message: "the package has no items"})
+	}
+	return items, errs, nil
+}
+
+func readZipXML(archive *zip.Reader, name string, v any) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return errors.New("the file is missing from the package")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, qtiMaxFileSize+1))
+	if err != nil {
+		return errors.New("the file can not be read")
+	}
+	if len(data) > qtiMaxFileSize {
+		return fmt.Errorf("the file is larger than %d MB", qtiMaxFileSize>>20)
+	}
+	// items written by hand or by other tools often use HTML entities like &nbsp;
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Entity = xml.HTMLEntity
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid XML: %v", err)
+	}
+	return nil
+}
+
+// question converts the item, which has to hold a single interaction
+func (item *qtiItem) question() (*schema.Question, error) {
+	text, interactions, err := readMarkup(item.Body.Inner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item body: %v", err)
+	}
+	if len(interactions) != 1 {
+		return nil, fmt.Errorf("items must have exactly one interaction, found %d", len(interactions))
+	}
+	interaction := interactions[0]
+	if interaction.Prompt != nil {
+		prompt, _, _ := readMarkup(interaction.Prompt.Inner)
+		text = strings.TrimSpace(text + " " + prompt)
+	}
+
+	var response qtiResponseDeclaration
+	for _, r := range item.Responses {
+		if r.Identifier == interaction.ResponseIdentifier {
+			response = r
+		}
+	}
+	correct := response.Correct
+	if len(correct) == 0 && response.Mapping != nil {
+		// items scored with map_response may only give the value of every response
+		for _, entry := range response.Mapping.Entries {
+			if entry.Value > 0 {
+				correct = append(correct, entry.Key)
+			}
+		}
+	}
+
+	q := &schema.Question{Question: text}
+	switch kind := interaction.XMLName.Local; kind {
+	case "choiceInteraction", "orderInteraction":
+		texts := map[string]string{}
+		for _, choice := range interaction.Choices {
+			option, _, _ := readMarkup(choice.Inner)
+			q.Options = append(q.Options, option)
+			texts[choice.Identifier] = option
+		}
+		answers, err := choiceTexts(texts, correct)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case kind == "orderInteraction":
+			q.Type, q.Answers = schema.Ordering, answers
+		case response.Cardinality == "multiple":
+			q.Type, q.Answers = schema.MultiSelect, answers
+		case len(answers) != 1:
+			return nil, errors.New("a single choice item needs exactly one correct response")
+		case isTrueFalse(q.Options):
+			q.Type, q.Answer, q.Options = schema.TrueFalse, strings.ToLower(answers[0]), nil
+		default:
+			q.Type, q.Answer = schema.SingleChoice, answers[0]
+		}
+
+	case "matchInteraction":
+		if len(interaction.MatchSets) != 2 {
+			return nil, errors.New("a match interaction needs two sets of choices")
+		}
+		options, matches := map[string]int{}, map[string]string{}
+		for i, choice := range interaction.MatchSets[0].Choices {
+			option, _, _ := readMarkup(choice.Inner)
+			q.Options = append(q.Options, option)
+			options[choice.Identifier] = i
+		}
+		for _, choice := range interaction.MatchSets[1].Choices {
+			match, _, _ := readMarkup(choice.Inner)
+			q.Matches = append(q.Matches, match)
+			matches[choice.Identifier] = match
+		}
+		q.Type, q.Answers = schema.Matching, make([]string, len(q.Options))
+		for _, pair := range correct {
+			fields := strings.Fields(pair)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid pair %q", pair)
+			}
+			i, found := options[fields[0]]
+			match, matched := matches[fields[1]]
+			if !found || !matched {
+				return nil, fmt.Errorf("the pair %q is not made of choices of the item", pair)
+			}
+			q.Answers[i] = match
+		}
+		if slices.Contains(q.Answers, "") {
+			return nil, errors.New("every choice of the first set needs a correct match")
+		}
+
+	case "textEntryInteraction":
+		if len(correct) == 0 {
+			return nil, errors.New("no correct response")
+		}
+		if response.BaseType == "float" || response.BaseType == "integer" {
+			q.Type, q.Answer = schema.Numeric, strings.TrimSpace(correct[0])
+			if item.Processing != nil {
+				q.Tolerance = readTolerance(item.Processing.Inner)
+			}
+			break
+		}
+		q.Type = schema.ShortText
+		for _, answer := range correct {
+			if !slices.Contains(q.Answers, answer) {
+				q.Answers = append(q.Answers, answer)
+			}
+		}
+		if response.Mapping != nil {
+			for _, entry := range response.Mapping.Entries {
+				if entry.Value > 0 && !slices.Contains(q.Answers, entry.Key) {
+					q.Answers = append(q.Answers, entry.Key)
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%s is not supported", kind)
+	}
+	return q, nil
+}
+
+// choiceTexts returns the text of the choices with the identifiers
+func choiceTexts(texts map[string]string, identifiers []string) ([]string, error) {
+	if len(identifiers) == 0 {
+		return nil, errors.New("no correct response")
+	}
+	answers := make([]string, len(identifiers))
+	for i, id := range identifiers {
+		text, found := texts[strings.TrimSpace(id)]
+		if !found {
+			return nil, fmt.Errorf("the correct response %q is not a choice of the item", id)
+		}
+		answers[i] = text
+	}
+	return answers, nil
+}
+
+func isTrueFalse(options []string) bool {
+	return len(options) == 2 && scoring.SameSet(options, []string{"true", "false"})
+}
+
+// readMarkup returns the text of XHTML content and the interactions found in it
+// Inline interactions are left as a blank in the text, unless they end it
+func readMarkup(inner string) (string, []qtiInteraction, error) {
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	var text strings.Builder
+	var interactions []qtiInteraction
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if strings.HasSuffix(t.Name.Local, "Interaction") {
+				var interaction qtiInteraction
+				if err := decoder.DecodeElement(&interaction, &t); err != nil {
+					return "", nil, err
+				}
+				interactions = append(interactions, interaction)
+				text.WriteString(" _____ ")
+				continue
+			}
+			text.WriteString(" ")
+		case xml.EndElement:
+			text.WriteString(" ")
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	plain := strings.Join(strings.Fields(text.String()), " ")
+	plain = strings.TrimSpace(strings.TrimSuffix(plain, "_____"))
+	return plain, interactions, nil
+}
+
+// readTolerance returns the absolute tolerance of the equal operator of the response processing, 0 if there is none
+func readTolerance(inner string) float64 {
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok || t.Name.Local != "equal" {
+			continue
+		}
+		mode, tolerance := "exact", ""
+		for _, attr := range t.Attr {
+			switch attr.Name.Local {
+			case "toleranceMode":
+				mode = attr.Value
+			case "tolerance":
+				tolerance = attr.Value
+			}
+		}
+		fields := strings.Fields(tolerance)
+		if mode != "absolute" || len(fields) == 0 {
+			return 0
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || value < 0 {
+			return 0
+		}
+		return value
+	}
+}
+
+// QTI has no equivalent of the patterns of short text questions
+func supportsQTI(q *schema.Question) bool {
+	if scoring.Type(q) == schema.ShortText {
+		return q.Pattern == "" && len(q.Answers) > 0
+	}
+	return true
+}
+
+// WriteQuizQTI writes the quiz as a QTI 2.1 package, with a test giving every item the points of its question
+func WriteQuizQTI(w io.Writer, quiz *schema.Quiz) error {
+	questions := make([]schema.Question, len(quiz.Questions))
+	for i, qq := range quiz.Questions {
+		questions[i] = qq.Question
+	}
+	for i := range questions {
+		if !Supports(QTI, &questions[i]) {
+			return fmt.Errorf("question %d can not be written as %s", questions[i].ID, QTI)
+		}
+	}
+	return writeQTI(w, fmt.Sprintf("Quiz %d", quiz.ID), questions, quiz)
+}
+
+// writeQTI writes a package with an item per question, and a test of the quiz if there is one
+func writeQTI(w io.Writer, title string, questions []schema.Question, quiz *schema.Quiz) error {
+	archive := zip.NewWriter(w)
+	manifest := qtiManifestFile{Xmlns: manifestNamespace, Identifier: "MANIFEST-1", Metadata: &qtiMetadata{"QTIv2.1 Package", "1.0.0"}}
+
+	refs := make([]qtiItemRef, len(questions))
+	for i := range questions {
+		id := fmt.Sprintf("item-%d", i+1)
+		href := "items/" + id + ".xml"
+		item, err := qtiItemFor(&questions[i], id)
+		if err != nil {
+			return err
+		}
+		if err := writeZipXML(archive, href, item); err != nil {
+			return err
+		}
+		manifest.Resources = append(manifest.Resources, qtiResource{Identifier: id, Type: qtiItemType, Href: href, Files: []qtiFile{{href}}})
+		refs[i] = qtiItemRef{Identifier: id, Href: href}
+	}
+
+	if quiz != nil {
+		test := qtiTest{Xmlns: qtiNamespace, Identifier: fmt.Sprintf("quiz-%d", quiz.ID), Title: title}
+		if quiz.Settings.TimeLimitMinutes > 0 {
+			test.TimeLimits = &qtiTimeLimits{MaxTime: quiz.Settings.TimeLimitMinutes * 60}
+		}
+		test.Part.Identifier, test.Part.NavigationMode, test.Part.SubmissionMode = "part-1", "nonlinear", "simultaneous"
+		test.Part.Section.Identifier, test.Part.Section.Title, test.Part.Section.Visible = "section-1", title, true
+		if quiz.PerStudentVariants {
+			test.Part.Section.Ordering = &qtiOrdering{Shuffle: true}
+		}
+		for i, qq := range quiz.Questions {
+			refs[i].Weight = &qtiWeight{"WEIGHT", qq.Points}
+		}
+		test.Part.Section.Items = refs
+		if err := writeZipXML(archive, "test.xml", test); err != nil {
+			return err
+		}
+		resource := qtiResource{Identifier: test.Identifier, Type: qtiTestType, Href: "test.xml", Files: []qtiFile{{"test.xml"}}}
+		for _, ref := range refs {
+			resource.Dependencies = append(resource.Dependencies, qtiDependency{ref.Identifier})
+		}
+		manifest.Resources = append([]qtiResource{resource}, manifest.Resources...)
+	}
+
+	if err := writeZipXML(archive, qtiManifest, manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeZipXML(archive *zip.Writer, name string, v any) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(file, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	return encoder.Encode(v)
+}
+
+// qtiItemFor converts the question to an item with the given identifier
+func qtiItemFor(q *schema.Question, id string) (qtiItem, error) {
+	item := qtiItem{
+		Xmlns:      qtiNamespace,
+		Identifier: id,
+		Title:      qtiTitle(q.Question),
+		Outcomes:   []qtiOutcomeDeclaration{{Identifier: "SCORE", Cardinality: "single", BaseType: "float"}},
+		Processing: &qtiResponseProcessing{Template: qtiMatchCorrect},
+	}
+	response := qtiResponseDeclaration{Identifier: qtiResponse, Cardinality: "single", BaseType: "identifier"}
+	interaction := qtiInteraction{ResponseIdentifier: qtiResponse, Prompt: &qtiMarkup{Inner: escapeXML(q.Question)}}
+	one, unlimited := 1, 0
+
+	choices := func(options []string) map[string]string {
+		ids := map[string]string{}
+		for i, option := range options {
+			choice := qtiChoice{Identifier: fmt.Sprintf("C%d", i+1), Inner: escapeXML(option)}
+			interaction.Choices = append(interaction.Choices, choice)
+			if _, found := ids[option]; !found {
+				ids[option] = choice.Identifier
+			}
+		}
+		return ids
+	}
+
+	switch scoring.Type(q) {
+	case schema.SingleChoice, schema.TrueFalse:
+		options := q.Options
+		if scoring.Type(q) == schema.TrueFalse {
+			options = []string{"true", "false"}
+		}
+		interaction.XMLName.Local, interaction.Shuffle, interaction.MaxChoices = "choiceInteraction", "false", &one
+		ids := choices(options)
+		response.Correct = []string{ids[q.Answer]}
+
+	case schema.MultiSelect:
+		interaction.XMLName.Local, interaction.Shuffle, interaction.MaxChoices = "choiceInteraction", "false", &unlimited
+		response.Cardinality = "multiple"
+		ids := choices(q.Options)
+		for _, answer := range q.Answers {
+			response.Correct = append(response.Correct, ids[answer])
+		}
+
+	case schema.Ordering:
+		interaction.XMLName.Local, interaction.Shuffle = "orderInteraction", "true"
+		response.Cardinality = "ordered"
+		ids := choices(q.Options)
+		for _, answer := range q.Answers {
+			response.Correct = append(response.Correct, ids[answer])
+		}
+
+	case schema.Matching:
+		associations := len(q.Options)
+		interaction.XMLName.Local, interaction.Shuffle, interaction.MaxAssociations = "matchInteraction", "true", &associations
+		response.Cardinality, response.BaseType = "multiple", "directedPair"
+		sources, targets := qtiMatchSet{}, qtiMatchSet{}
+		for i, option := range q.Options {
+			sources.Choices = append(sources.Choices, qtiChoice{Identifier: fmt.Sprintf("S%d", i+1), MatchMax: &one, Inner: escapeXML(option)})
+		}
+		for i, match := range q.Matches {
+			targets.Choices = append(targets.Choices, qtiChoice{Identifier: fmt.Sprintf("T%d", i+1), MatchMax: &unlimited, Inner: escapeXML(match)})
+		}
+		interaction.MatchSets = []qtiMatchSet{sources, targets}
+		for i, answer := range q.Answers {
+			if j := slices.Index(q.Matches, answer); j >= 0 {
+				response.Correct = append(response.Correct, fmt.Sprintf("S%d T%d", i+1, j+1))
+			}
+		}
+
+	case schema.Numeric, schema.ShortText:
+		// text entries are inline, the question goes in a paragraph before them
+		entry, err := xml.Marshal(qtiInteraction{XMLName: xml.Name{Local: "textEntryInteraction"}, ResponseIdentifier: qtiResponse})
+		if err != nil {
+			return item, err
+		}
+		item.Body.Inner = "<p>" + escapeXML(q.Question) + "</p><p>" + string(entry) + "</p>"
+		if scoring.Type(q) == schema.Numeric {
+			response.BaseType, response.Correct = "float", []string{q.Answer}
+			if q.Tolerance > 0 {
+				tolerance := strconv.FormatFloat(q.Tolerance, 'f', -1, 64)
+				item.Processing = &qtiResponseProcessing{Inner: `<responseCondition><responseIf>` +
+					`<equal toleranceMode="absolute" tolerance="` + tolerance + ` ` + tolerance + `">` +
+					`<variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></equal>` +
+					`<setOutcomeValue identifier="SCORE"><baseValue baseType="float">1</baseValue></setOutcomeValue>` +
+					`</responseIf></responseCondition>`}
+			}
+		} else {
+			// every accepted variant is worth the whole question
+			response.BaseType, response.Correct = "string", q.Answers[:1]
+			response.Mapping = &qtiMapping{UpperBound: 1}
+			for _, answer := range q.Answers {
+				response.Mapping.Entries = append(response.Mapping.Entries, qtiMapEntry{Key: answer, Value: 1})
+			}
+			item.Processing = &qtiResponseProcessing{Template: qtiMapResponse}
+		}
+		item.Responses = []qtiResponseDeclaration{response}
+		return item, nil
+	}
+
+	body, err := xml.Marshal(interaction)
+	if err != nil {
+		return item, err
+	}
+	item.Body.Inner = string(body)
+	item.Responses = []qtiResponseDeclaration{response}
+	return item, nil
+}
+
+// qtiTitle shortens the question to the title of its item
+func qtiTitle(question string) string {
+	title := strings.Join(strings.Fields(question), " ")
+	if runes := []rune(title); len(runes) > 80 {
+		title = string(runes[:77]) + "..."
+	}
+	return title
+}
+
+func escapeXML(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package questionfmt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// Format is a text format questions can be imported from and exported to
+type Format string
+
+const (
+	GIFT  Format = "gift"  // Moodle GIFT
+	Aiken Format = "aiken" // Moodle Aiken, single choice questions only
+	CSV   Format = "csv"   // one question per row, with a header row
+	QTI   Format = "qti"   // IMS QTI 2.1 zip package
+)
+
+var ErrUnknownFormat = errors.New("format must be one of gift, aiken, csv or qti")
+
+func ParseFormat(value string) (Format, error) {
+	format := Format(strings.ToLower(value))
+	switch format {
+	case GIFT, Aiken, CSV, QTI:
+		return format, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Item is a parsed question along with where it is in the input
+// Questions of text formats are located by the line they start on, those of packages by their file
+type Item struct {
+	Line     int             `json:"line,omitempty"`
+	File     string          `json:"file,omitempty"`
+	Question schema.Question `json:"question"`
+}
+
+// LineError is a question of the input which could not be parsed
+type LineError struct {
+	Line    int    `json:"line,omitempty"`
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e LineError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Parse reads every question of the input
+// Questions which can not be parsed are reported as line errors and the others are still returned,
+// the error is only set when the input itself can not be read
+func Parse(format Format, r io.Reader) ([]Item, []LineError, error) {
+	switch format {
+	case GIFT:
+		return parseGIFT(r)
+	case Aiken:
+		return parseAiken(r)
+	case CSV:
+		return parseCSV(r)
+	case QTI:
+		return parseQTI(r)
+	}
+	return nil, nil, ErrUnknownFormat
+}
+
+// Supports reports if the question can be written in the format without losing its answer
+func Supports(format Format, q *schema.Question) bool {
+	switch format {
+	case GIFT:
+		return supportsGIFT(q)
+	case Aiken:
+		return supportsAiken(q)
+	case CSV:
+		return supportsCSV(q)
+	case QTI:
+		return supportsQTI(q)
+	}
+	return false
+}
+
+// Write writes the questions in the format, they all have to be supported by it
+func Write(format Format, w io.Writer, questions []schema.Question) error {
+	for i := range questions {
+		if !Supports(format, &questions[i]) {
+			return fmt.Errorf("question %d can not be written as %s", questions[i].ID, format)
+		}
+	}
+	switch format {
+	case GIFT:
+		return writeGIFT(w, questions)
+	case Aiken:
+		return writeAiken(w, questions)
+	case CSV:
+		return writeCSV(w, questions)
+	case QTI:
+		return writeQTI(w, "Question bank", questions, nil)
+	}
+	return ErrUnknownFormat
+}
@@ -0,0 +1,383 @@
+package questionfmt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+func parse(t *testing.T, format Format, input string) ([]Item, []LineError) {
+	t.Helper()
+	items, errs, err := Parse(format, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", format, err)
+	}
+	return items, errs
+}
+
+func TestParseGIFT_QuestionTypes(t *testing.T) {
+	input := `// a Moodle export
+$CATEGORY: $course$/Geography
+
+// [tag:geography] [tag:europe] [difficulty:easy]
+::Capital:: What is the capital of France? {
+	=Paris
+	~Berlin#No, that is Germany
+	~Madrid
+}
+
+Which are prime numbers? {~%50%2 ~%50%3 ~%-100%4}
+
+The sun is a star {T}
+
+Pi to two decimals {#3.14:0.01}
+
+A number between one and five {#1..5}
+
+Chemical formula of water {=H2O =h2o}
+
+Match the countries {
+	=France -> Paris
+	=Spain -> Madrid
+	= -> Rome
+}
+
+Escaped \{braces\} and a 1\:2 ratio {=yes ~no}
+`
+	items, errs := parse(t, GIFT, input)
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	want := []Item{
+		{Line: 5, Question: schema.Question{Type: schema.SingleChoice, Question: "What is the capital of France?", Options: []string{"Paris", "Berlin", "Madrid"}, Answer: "Paris", Difficulty: "EASY",
+			Tags: []schema.QuestionTag{{Name: "geography"}, {Name: "europe"}}}},
+		{Line: 11, Question: schema.Question{Type: schema.MultiSelect, Question: "Which are prime numbers?", Options: []string{"2", "3", "4"}, Answers: []string{"2", "3"}}},
+		{Line: 13, Question: schema.Question{Type: schema.TrueFalse, Question: "The sun is a star", Answer: "true"}},
+		{Line: 15, Question: schema.Question{Type: schema.Numeric, Question: "Pi to two decimals", Answer: "3.14", Tolerance: 0.01}},
+		{Line: 17, Question: schema.Question{Type: schema.Numeric, Question: "A number between one and five", Answer: "3", Tolerance: 2}},
+		{Line: 19, Question: schema.Question{Type: schema.ShortText, Question: "Chemical formula of water", Answers: []string{"H2O", "h2o"}}},
+		{Line: 21, Question: schema.Question{Type: schema.Matching, Question: "Match the countries", Options: []string{"France", "Spain"}, Answers: []string{"Paris", "Madrid"},
+			Matches: []string{"Paris", "Madrid", "Rome"}}},
+		{Line: 27, Question: schema.Question{Type: schema.SingleChoice, Question: "Escaped {braces} and a 1:2 ratio", Options: []string{"yes", "no"}, Answer: "yes"}},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected %+v, got %+v", want, items)
+	}
+}
+
+func TestParseGIFT_ReportsErrorsPerQuestion(t *testing.T) {
+	input := `Write an essay {}
+
+No answer block
+
+Fine {=a ~b}
+
+No correct answer {~a ~b}
+
+Not closed {=a ~b
+`
+	items, errs := parse(t, GIFT, input)
+	if len(items) != 1 || items[0].Line != 5 {
+		t.Errorf("Expected the question on line 5 to be parsed, got %+v", items)
+	}
+	lines := []int{}
+	for _, e := range errs {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{1, 3, 7, 9}) {
+		t.Errorf("Expected errors on lines 1, 3, 7 and 9, got %v", errs)
+	}
+}
+
+func TestParseAiken(t *testing.T) {
+	input := `What is the capital of France?
+A. Berlin
+B) Paris
+ANSWER: B
+
+Missing answer
+A. one
+B. two
+
+Skipped options
+A. one
+C. three
+ANSWER: A
+
+Answer out of range
+A. one
+B. two
+ANSWER: D
+
+Which planet is
+known as the red planet?
+A. Mars
+B. Venus
+ANSWER: A
+`
+	items, errs := parse(t, Aiken, input)
+	want := []Item{
+		{Line: 1, Question: schema.Question{Type: schema.SingleChoice, Question: "What is the capital of France?", Options: []string{"Berlin", "Paris"}, Answer: "Paris"}},
+		{Line: 20, Question: schema.Question{Type: schema.SingleChoice, Question: "Which planet is known as the red planet?", Options: []string{"Mars", "Venus"}, Answer: "Mars"}},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected %+v, got %+v", want, items)
+	}
+	wantErrs := []LineError{
+		{Line: 6, Message: "missing ANSWER line"},
+		{Line: 12, Message: "expected option B, got C"},
+		{Line: 18, Message: "the answer D is not one of the options"},
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("Expected errors %v, got %v", wantErrs, errs)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufeffQuestion,type,options,answer,tolerance,tags\n" +
+		"What is 2+2?,single_choice,3|4|5,4,,math|easy\n" +
+		"\"Pi, to two decimals\",NUMERIC,,3.14,0.01,\n" +
+		"Bad tolerance,NUMERIC,,1,abc,\n"
+	items, errs := parse(t, CSV, input)
+	want := []Item{
+		{Line: 2, Question: schema.Question{Type: schema.SingleChoice, Question: "What is 2+2?", Options: []string{"3", "4", "5"}, Answer: "4",
+			Tags: []schema.QuestionTag{{Name: "math"}, {Name: "easy"}}}},
+		{Line: 3, Question: schema.Question{Type: schema.Numeric, Question: "Pi, to two decimals", Answer: "3.14", Tolerance: 0.01}},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected %+v, got %+v", want, items)
+	}
+	if len(errs) != 1 || errs[0].Line != 4 {
+		t.Errorf("Expected an error on line 4, got %v", errs)
+	}
+
+	_, errs = parse(t, CSV, "question,points\nWhat?,1\n")
+	if len(errs) != 1 || errs[0].Line != 1 {
+		t.Errorf("Expected the unknown column to be reported on line 1, got %v", errs)
+	}
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	questions := []schema.Question{
+		{Type: schema.SingleChoice, Question: "Capital of France? {really}", Options: []string{"Paris", "Berlin"}, Answer: "Paris", Difficulty: schema.Easy,
+			Tags: []schema.QuestionTag{{Name: "geography"}}},
+		{Type: schema.MultiSelect, Question: "Primes", Options: []string{"2", "3", "4"}, Answers: []string{"2", "3"}, Difficulty: schema.Medium},
+		{Type: schema.TrueFalse, Question: "The sun is a star", Answer: "false", Difficulty: schema.Medium},
+		{Type: schema.Numeric, Question: "Pi", Answer: "3.14", Tolerance: 0.01, Difficulty: schema.Hard},
+		{Type: schema.ShortText, Question: "Formula of water", Answers: []string{"H2O", "a=b"}, Difficulty: schema.Medium},
+		{Type: schema.Matching, Question: "Match", Options: []string{"France", "Spain"}, Answers: []string{"Paris", "Madrid"}, Matches: []string{"Paris", "Madrid", "Rome"}, Difficulty: schema.Medium},
+	}
+	for _, format := range []Format{GIFT, CSV} {
+		var buf bytes.Buffer
+		if err := Write(format, &buf, questions); err != nil {
+			t.Fatalf("Failed to write %s: %v", format, err)
+		}
+		items, errs := parse(t, format, buf.String())
+		if len(errs) != 0 {
+			t.Fatalf("%s: expected no errors, got %v\n%s", format, errs, buf.String())
+		}
+		got := make([]schema.Question, len(items))
+		for i, item := range items {
+			got[i] = item.Question
+		}
+		if !reflect.DeepEqual(got, questions) {
+			t.Errorf("%s: expected %+v, got %+v\n%s", format, questions, got, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(Aiken, &buf, questions[:1]); err != nil {
+		t.Fatalf("Failed to write aiken: %v", err)
+	}
+	items, _ := parse(t, Aiken, buf.String())
+	if len(items) != 1 || items[0].Question.Answer != "Paris" {
+		t.Errorf("Expected the aiken question to be read back, got %+v\n%s", items, buf.String())
+	}
+}
+
+func TestSupports(t *testing.T) {
+	ordering := schema.Question{Type: schema.Ordering, Options: []string{"a", "b"}, Answers: []string{"a", "b"}}
+	pattern := schema.Question{Type: schema.ShortText, Pattern: "colou?r"}
+	pipe := schema.Question{Options: []string{"a|b", "c"}, Answer: "c"}
+	tests := []struct {
+		format   Format
+		question schema.Question
+		want     bool
+	}{
+		{GIFT, ordering, false},
+		{GIFT, pattern, false},
+		{CSV, ordering, true},
+		{CSV, pattern, true},
+		{CSV, pipe, false},
+		{Aiken, pipe, true},
+		{Aiken, schema.Question{Type: schema.TrueFalse, Answer: "true"}, false},
+	}
+	for _, tt := range tests {
+		if got := Supports(tt.format, &tt.question); got != tt.want {
+			t.Errorf("%s %s: expected %v, got %v", tt.format, tt.question.Type, tt.want, got)
+		}
+	}
+	if err := Write(GIFT, &bytes.Buffer{}, []schema.Question{ordering}); err == nil {
+		t.Error("Expected an error writing an ordering question as GIFT")
+	}
+}
+
+func TestWriteQuizQTI_RoundTrip(t *testing.T) {
+	questions := []schema.Question{
+		{Type: schema.SingleChoice, Question: "Capital of France? <b>Not</b> Berlin & co", Options: []string{"Paris", "Berlin"}, Answer: "Paris"},
+		{Type: schema.MultiSelect, Question: "Primes", Options: []string{"2", "3", "4"}, Answers: []string{"2", "3"}},
+		{Type: schema.TrueFalse, Question: "The sun is a star", Answer: "true"},
+		{Type: schema.Numeric, Question: "Pi", Answer: "3.14", Tolerance: 0.01},
+		{Type: schema.ShortText, Question: "Formula of water", Answers: []string{"H2O", "h2o"}},
+		{Type: schema.Ordering, Question: "Order the planets", Options: []string{"Mars", "Mercury", "Earth"}, Answers: []string{"Mercury", "Earth", "Mars"}},
+		{Type: schema.Matching, Question: "Match", Options: []string{"France", "Spain"}, Answers: []string{"Paris", "Madrid"}, Matches: []string{"Rome", "Madrid", "Paris"}},
+	}
+	quiz := schema.Quiz{ID: 7, Settings: schema.QuizSettings{TimeLimitMinutes: 10}}
+	for i, q := range questions {
+		quiz.Questions = append(quiz.Questions, schema.QuizQuestion{Question: q, Position: i, Points: float64(i + 1)})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteQuizQTI(&buf, &quiz); err != nil {
+		t.Fatalf("Failed to write the quiz: %v", err)
+	}
+	items, errs := parse(t, QTI, buf.String())
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	got := make([]schema.Question, len(items))
+	for i, item := range items {
+		got[i] = item.Question
+	}
+	if !reflect.DeepEqual(got, questions) {
+		t.Errorf("Expected %+v, got %+v", questions, got)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to open the package: %v", err)
+	}
+	var test qtiTest
+	if err := readZipXML(archive, "test.xml", &test); err != nil {
+		t.Fatalf("Failed to read the test: %v", err)
+	}
+	if test.TimeLimits == nil || test.TimeLimits.MaxTime != 600 || len(test.Part.Section.Items) != len(questions) {
+		t.Fatalf("Unexpected test %+v", test)
+	}
+	if weight := test.Part.Section.Items[3].Weight; weight == nil || weight.Value != 4 {
+		t.Errorf("Expected the fourth item to weigh 4 points, got %+v", weight)
+	}
+}
+
+// qtiPackage zips the files with a manifest listing them as items
+func qtiPackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	manifest := qtiManifestFile{Identifier: "M"}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		manifest.Resources = append(manifest.Resources, qtiResource{Identifier: name, Type: qtiItemType, Href: name})
+		w, _ := archive.Create(name)
+		io.WriteString(w, files[name])
+	}
+	w, _ := archive.Create(qtiManifest)
+	xml.NewEncoder(w).Encode(manifest)
+	archive.Close()
+	return buf.String()
+}
+
+func TestParseQTI_ForeignItems(t *testing.T) {
+	input := qtiPackage(t, map[string]string{
+		"blank.xml": `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="blank" title="Blank">
+  <responseDeclaration identifier="R" cardinality="single" baseType="string">
+    <mapping defaultValue="0"><mapEntry mapKey="Paris" mappedValue="1"/><mapEntry mapKey="paris" mappedValue="1"/></mapping>
+  </responseDeclaration>
+  <itemBody><p>The capital of France is <textEntryInteraction responseIdentifier="R"/>&nbsp;today.</p></itemBody>
+</assessmentItem>`,
+		"choice.xml": `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="choice" title="Choice">
+  <responseDeclaration identifier="R" cardinality="multiple" baseType="identifier">
+    <mapping defaultValue="0"><mapEntry mapKey="A" mappedValue="0.5"/><mapEntry mapKey="B" mappedValue="-1"/><mapEntry mapKey="C" mappedValue="0.5"/></mapping>
+  </responseDeclaration>
+  <itemBody>
+    <div><p>Pick the <em>even</em> numbers</p></div>
+    <choiceInteraction responseIdentifier="R" maxChoices="0">
+      <simpleChoice identifier="A">2</simpleChoice><simpleChoice identifier="B">3</simpleChoice><simpleChoice identifier="C">4</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`,
+		"essay.xml": `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="essay" title="Essay">
+  <itemBody><extendedTextInteraction responseIdentifier="R"><prompt>Discuss</prompt></extendedTextInteraction></itemBody>
+</assessmentItem>`,
+		"test.xml": `<assessmentTest identifier="t" title="t"/>`,
+	})
+	items, errs := parse(t, QTI, input)
+	want := []Item{
+		{File: "blank.xml", Question: schema.Question{Type: schema.ShortText, Question: "The capital of France is _____ today.", Answers: []string{"Paris", "paris"}}},
+		{File: "choice.xml", Question: schema.Question{Type: schema.MultiSelect, Question: "Pick the even numbers", Options: []string{"2", "3", "4"}, Answers: []string{"2", "4"}}},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected %+v, got %+v", want, items)
+	}
+	wantErrs := []LineError{
+		{File: "essay.xml", Message: "extendedTextInteraction is not supported"},
+		{File: "test.xml", Message: "invalid XML: expected element type <assessmentItem> but have <assessmentTest>"},
+	}
+	if !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("Expected errors %v, got %v", wantErrs, errs)
+	}
+}
+
+func TestParseQTI_ItemLimits(t *testing.T) {
+	item := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="i" title="i">
+  <responseDeclaration identifier="R" cardinality="single" baseType="identifier"><correctResponse><value>A</value></correctResponse></responseDeclaration>
+  <itemBody><choiceInteraction responseIdentifier="R" maxChoices="1"><prompt>Pick A</prompt><simpleChoice identifier="A">A</simpleChoice><simpleChoice identifier="B">B</simpleChoice></choiceInteraction></itemBody>
+</assessmentItem>`
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, _ := archive.Create("item.xml")
+	io.WriteString(w, item)
+	manifest := qtiManifestFile{Identifier: "M", Resources: []qtiResource{
+		{Identifier: "a", Type: qtiItemType, Href: "item.xml"},
+		{Identifier: "b", Type: qtiItemType, Href: "./item.xml"},
+	}}
+	w, _ = archive.Create(qtiManifest)
+	xml.NewEncoder(w).Encode(manifest)
+	archive.Close()
+
+	items, errs := parse(t, QTI, buf.String())
+	if len(items) != 1 || len(errs) != 1 || errs[0].File != "item.xml" {
+		t.Errorf("Expected the item once and the duplicate reported, got %v and %v", items, errs)
+	}
+
+	files := map[string]string{}
+	for i := range qtiMaxItems + 1 {
+		files[fmt.Sprintf("item%d.xml", i)] = item
+	}
+	items, errs = parse(t, QTI, qtiPackage(t, files))
+	if len(items) != 0 || len(errs) != 1 || errs[0].File != qtiManifest {
+		t.Errorf("Expected a package with too many items to be rejected, got %d items and %v", len(items), errs)
+	}
+}
+
+func TestParseQTI_InvalidPackage(t *testing.T) {
+	_, errs := parse(t, QTI, "not a zip")
+	if len(errs) != 1 {
+		t.Errorf("Expected the file to be reported, got %v", errs)
+	}
+
+	var buf bytes.Buffer
+	zip.NewWriter(&buf).Close()
+	_, errs = parse(t, QTI, buf.String())
+	if len(errs) != 1 || errs[0].File != qtiManifest {
+		t.Errorf("Expected the missing manifest to be reported, got %v", errs)
+	}
+}
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -12,6 +13,45 @@ const (
 	Admin    Role = "ADMIN"
 )
 
+type Difficulty string
+
+const (
+	Easy   Difficulty = "EASY"
+	Medium Difficulty = "MEDIUM"
+	Hard   Difficulty = "HARD"
+)
+
+// QuestionType decides how a question is answered and graded
+type QuestionType string
+
+const (
+	SingleChoice QuestionType = "SINGLE_CHOICE" // Answer is one of Options
+	MultiSelect  QuestionType = "MULTI_SELECT"  // Answers are the correct Options
+	TrueFalse    QuestionType = "TRUE_FALSE"    // Answer is "true" or "false"
+	Numeric      QuestionType = "NUMERIC"       // Answer is a number, accepted within Tolerance
+	ShortText    QuestionType = "SHORT_TEXT"    // Answers are accepted variants, Pattern an accepted regex
+	Ordering     QuestionType = "ORDERING"      // Answers are the Options in the correct order
+	Matching     QuestionType = "MATCHING"      // Answers[i] is the entry of Matches that goes with Options[i]
+)
+
+// LatePolicy decides what happens to an attempt submitted after its deadline
+type LatePolicy string
+
+const (
+	LateReject   LatePolicy = "REJECT"   // the submission is refused and the attempt closed without answers
+	LateAccept   LatePolicy = "ACCEPT"   // the submission is graded and flagged as late
+	LatePenalize LatePolicy = "PENALIZE" // the submission is flagged as late and LatePenalty of its score is taken off
+)
+
+// GradePolicy decides which attempts of a quiz count towards the grade of a student
+type GradePolicy string
+
+const (
+	GradeBest    GradePolicy = "BEST"    // the highest score
+	GradeLatest  GradePolicy = "LATEST"  // the score of the last submitted attempt
+	GradeAverage GradePolicy = "AVERAGE" // the average score of every attempt
+)
+
 type User struct {
 	ID        uint      `gorm:"primaryKey"`
 	UID       string    `gorm:"uniqueIndex"`
@@ -22,25 +62,195 @@ type User struct {
 }
 
 type Course struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	Title       string      `json:"title"`
+	User        User        `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	UserID      uint        `json:"user_id" gorm:"constraint:OnDelete:CASCADE;"`
+	GradePolicy GradePolicy `json:"grade_policy" gorm:"default:BEST"` // how the gradebook of the course counts attempts
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Module is an ordered section of a course
+type Module struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	Title     string    `json:"title"`
-	User      User      `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
-	UserID    uint      `json:"user_id" gorm:"constraint:OnDelete:CASCADE;"`
+	Course    Course    `json:"-" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE;"`
+	CourseID  uint      `json:"course_id" gorm:"index;constraint:OnDelete:CASCADE;"`
+	Title     string    `json:"title" gorm:"not null"`
+	Position  int       `json:"position"` // ordering index inside the course, starting at 0
+	Lessons   []Lesson  `json:"lessons" gorm:"foreignKey:ModuleID;constraint:OnDelete:CASCADE;"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Lesson is an ordered piece of content of a module
+type Lesson struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Module           Module    `json:"-" gorm:"foreignKey:ModuleID;constraint:OnDelete:CASCADE;"`
+	ModuleID         uint      `json:"module_id" gorm:"index;constraint:OnDelete:CASCADE;"`
+	Title            string    `json:"title" gorm:"not null"`
+	Body             string    `json:"body"` // markdown
+	EstimatedMinutes int       `json:"estimated_minutes"`
+	Position         int       `json:"position"` // ordering index inside the module, starting at 0
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type EnrollmentStatus string
+
+const (
+	EnrollmentActive    EnrollmentStatus = "ACTIVE"
+	EnrollmentDropped   EnrollmentStatus = "DROPPED"
+	EnrollmentCompleted EnrollmentStatus = "COMPLETED"
+)
+
+// Enrollment links a student to a course, there is at most one per student and course
+type Enrollment struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	User      User             `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	UserID    uint             `json:"user_id" gorm:"uniqueIndex:idx_enrollment_user_course;constraint:OnDelete:CASCADE;"`
+	Course    Course           `json:"course" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE;"`
+	CourseID  uint             `json:"course_id" gorm:"uniqueIndex:idx_enrollment_user_course;index;constraint:OnDelete:CASCADE;"`
+	Status    EnrollmentStatus `json:"status" gorm:"not null"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// Question is an entry of the question bank of a course
+// Quizzes reference the questions they were generated from through QuizQuestion
+type Question struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	Course     Course        `json:"-" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE;"`
+	CourseID   uint          `json:"course_id" gorm:"index;constraint:OnDelete:CASCADE;"`
+	Type       QuestionType  `json:"type" gorm:"index"` // empty means SINGLE_CHOICE, for questions stored before types existed
+	Question   string        `json:"question" gorm:"not null"`
+	Options    []string      `json:"options" gorm:"serializer:json"`
+	Answer     string        `json:"answer,omitempty"`
+	Answers    []string      `json:"answers,omitempty" gorm:"serializer:json"`
+	Matches    []string      `json:"matches,omitempty" gorm:"serializer:json"`
+	Tolerance  float64       `json:"tolerance,omitempty"`
+	Pattern    string        `json:"pattern,omitempty"`
+	Difficulty Difficulty    `json:"difficulty" gorm:"index"`
+	Tags       []QuestionTag `json:"tags" gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE;"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// QuestionTag is encoded as a plain string in JSON
+type QuestionTag struct {
+	ID         uint   `gorm:"primaryKey"`
+	QuestionID uint   `gorm:"index;constraint:OnDelete:CASCADE;"`
+	Name       string `gorm:"index;not null"`
+}
+
+func (t QuestionTag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+func (t *QuestionTag) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Name)
+}
+
+// QuizQuestion places a question of the bank at a position of a quiz
+// In JSON it is the question itself along with its position and points
+type QuizQuestion struct {
+	QuizID     uint     `gorm:"primaryKey;constraint:OnDelete:CASCADE;"`
+	Position   int      `gorm:"primaryKey;autoIncrement:false"` // starting at 0
+	Question   Question `gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE;"`
+	QuestionID uint     `gorm:"index;not null"`
+	Points     float64  `gorm:"not null;default:1"` // weight of the question in the score of the quiz
+	// options and matches of the question in the order they were shuffled to with the seed of the quiz,
+	// empty for quizzes stored before options were shuffled
+	Options []string `gorm:"serializer:json"`
+	Matches []string `gorm:"serializer:json"`
+}
+
+// quizQuestionJSON is the JSON layout of QuizQuestion
+type quizQuestionJSON struct {
+	Question
+	Position int     `json:"position"`
+	Points   float64 `json:"points"`
+}
+
+func (qq QuizQuestion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(quizQuestionJSON{Question: qq.Question, Position: qq.Position, Points: qq.Points})
+}
+
+func (qq *QuizQuestion) UnmarshalJSON(data []byte) error {
+	var decoded quizQuestionJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*qq = QuizQuestion{Question: decoded.Question, QuestionID: decoded.Question.ID, Position: decoded.Position, Points: decoded.Points,
+		Options: decoded.Question.Options, Matches: decoded.Question.Matches}
+	return nil
+}
+
+// ScoringRules decide how the answers to a quiz are scored
+type ScoringRules struct {
+	PartialCredit   bool    `json:"partial_credit"`   // multi-select answers earn credit for every correct pick
+	NegativeMarking float64 `json:"negative_marking"` // fraction of its points taken off for a wrong answer
+	PassingScore    float64 `json:"passing_score"`    // fraction of the max score needed to pass, 0 if the quiz has no pass mark
+}
+
+// QuizSettings control when and how often students can take a quiz
+// Zero values mean no restriction
+type QuizSettings struct {
+	OpensAt          *time.Time   `json:"opens_at"`
+	ClosesAt         *time.Time   `json:"closes_at"`
+	TimeLimitMinutes int          `json:"time_limit_minutes"` // time to submit an attempt once started
+	MaxAttempts      int          `json:"max_attempts"`       // submitted attempts allowed per student
+	LatePolicy       LatePolicy   `json:"late_policy" gorm:"default:REJECT"`
+	LatePenalty      float64      `json:"late_penalty"` // fraction of the score taken off with the PENALIZE policy
+	Scoring          ScoringRules `json:"scoring" gorm:"embedded"`
+}
+
+// Quiz questions are picked from the bank with Seed, so together with
+// the recorded filters the same quiz can be generated again
 type Quiz struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Questions string    `json:"questions"` // JSON encoded questions
-	Course    Course    `json:"course" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE;"`
-	CourseID  uint      `json:"course_id" gorm:"constraint:OnDelete:CASCADE;"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Questions          []QuizQuestion `json:"questions" gorm:"foreignKey:QuizID;constraint:OnDelete:CASCADE;"`
+	Course             Course         `json:"course" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE;"`
+	CourseID           uint           `json:"course_id" gorm:"constraint:OnDelete:CASCADE;"`
+	Lesson             *Lesson        `json:"-" gorm:"foreignKey:LessonID;constraint:OnDelete:SET NULL;"`
+	LessonID           *uint          `json:"lesson_id" gorm:"index"` // optional, the lesson the quiz is attached to
+	Seed               int64          `json:"seed"`
+	Tag                string         `json:"tag"`
+	Difficulty         Difficulty     `json:"difficulty"`
+	QuestionType       QuestionType   `json:"question_type"`
+	Generator          string         `json:"generator"`            // generator that wrote the questions, empty for older quizzes
+	PerStudentVariants bool           `json:"per_student_variants"` // every student gets their own order of questions and options
+	Adaptive           bool           `json:"adaptive"`             // questions are served one at a time, picked by the ability of the student
+	AdaptiveLength     int            `json:"adaptive_length"`      // questions served in an adaptive attempt, all of them if 0
+	Settings           QuizSettings   `json:"settings" gorm:"embedded"`
+	CreatedAt          time.Time      `json:"created_at"`
+}
+
+// QuizAttempt is a single attempt of a user at a quiz (previously QuizzesTaken)
+// SubmittedAt is nil while the attempt is still in progress
+type QuizAttempt struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	User        User            `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	UserID      uint            `json:"user_id" gorm:"constraint:OnDelete:CASCADE;"`
+	Quiz        Quiz            `json:"quiz" gorm:"foreignKey:QuizID;constraint:OnDelete:CASCADE;"`
+	QuizID      uint            `json:"quiz_id" gorm:"constraint:OnDelete:CASCADE;"`
+	Score       float64         `json:"score"`
+	MaxScore    float64         `json:"max_score"`
+	Answers     []AttemptAnswer `json:"answers" gorm:"foreignKey:AttemptID;constraint:OnDelete:CASCADE;"`
+	VariantSeed int64           `json:"variant_seed"` // seed of the variant served, 0 if the quiz was served as is
+	StartedAt   time.Time       `json:"started_at"`
+	Deadline    *time.Time      `json:"deadline"` // set when the attempt started, nil if it has no time limit
+	SubmittedAt *time.Time      `json:"submitted_at"`
+	Late        bool            `json:"late"`    // submitted after the deadline
+	Passed      *bool           `json:"passed"`  // nil if the quiz has no passing score
+	Ability     *float64        `json:"ability"` // ability estimate of an adaptive attempt, nil for other attempts
+	Pending     *int            `json:"pending"` // position of the question of an adaptive attempt waiting for an answer
 }
 
-type QuizzesTaken struct {
-	ID     uint `json:"id" gorm:"primaryKey"`
-	User   User `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
-	UserID uint `json:"user_id" gorm:"constraint:OnDelete:CASCADE;"`
-	Quiz   Quiz `json:"quiz" gorm:"foreignKey:QuizID;constraint:OnDelete:CASCADE;"`
-	QuizID uint `json:"quiz_id" gorm:"constraint:OnDelete:CASCADE;"`
+// AttemptAnswer is the answer given to one question of a quiz in an attempt
+type AttemptAnswer struct {
+	ID        uint     `json:"id" gorm:"primaryKey"`
+	AttemptID uint     `json:"attempt_id" gorm:"index;constraint:OnDelete:CASCADE;"`
+	Position  int      `json:"position"` // index of the question in the quiz, not in the variant
+	Answer    string   `json:"answer"`
+	Answers   []string `json:"answers,omitempty" gorm:"serializer:json"` // multi-select, ordering and matching answers
+	Points    float64  `json:"points"`                                   // points earned, negative for a wrong answer with negative marking
+	Correct   bool     `json:"correct"`
+	TimeSpent *float64 `json:"time_spent,omitempty"` // seconds spent on the question as reported by the client
 }
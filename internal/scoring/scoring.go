@@ -0,0 +1,214 @@
+package scoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+// Response is the answer of a student to one question
+// Single values are sent as a string (or a number for numeric questions, a boolean for true/false ones),
+// multi-select, ordering and matching answers as an array of strings
+type Response []string
+
+func (r *Response) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*r = nil
+		return nil
+	case bytes.Equal(data, []byte("true")), bytes.Equal(data, []byte("false")):
+		*r = Response{string(data)}
+		return nil
+	case len(data) > 0 && data[0] == '[':
+		var values []string
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		*r = values
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*r = Response{value}
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return errors.New("an answer must be a string, a number, a boolean or an array of strings")
+	}
+	*r = Response{number.String()}
+	return nil
+}
+
+// Single returns the answer to a question expecting one value
+func (r Response) Single() string {
+	if len(r) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(r[0])
+}
+
+// Blank reports if the question was left unanswered
+func (r Response) Blank() bool {
+	return !slices.ContainsFunc(r, func(v string) bool { return strings.TrimSpace(v) != "" })
+}
+
+// Type returns the type of the question, questions stored before types existed are single choice
+func Type(q *schema.Question) schema.QuestionType {
+	if q.Type == "" {
+		return schema.SingleChoice
+	}
+	return q.Type
+}
+
+// IsList reports if the answers to the question are a list of values
+func IsList(q *schema.Question) bool {
+	switch Type(q) {
+	case schema.MultiSelect, schema.Ordering, schema.Matching:
+		return true
+	}
+	return false
+}
+
+// Correct reports if the response is a correct answer to the question
+// Text comparisons ignore case and surrounding spaces
+func Correct(q *schema.Question, r Response) bool {
+	switch Type(q) {
+	case schema.SingleChoice, schema.TrueFalse:
+		return strings.EqualFold(r.Single(), strings.TrimSpace(q.Answer))
+	case schema.MultiSelect:
+		return SameSet(r, q.Answers)
+	case schema.Numeric:
+		got, err := strconv.ParseFloat(r.Single(), 64)
+		if err != nil {
+			return false
+		}
+		want, err := strconv.ParseFloat(q.Answer, 64)
+		if err != nil {
+			return false
+		}
+		return math.Abs(got-want) <= q.Tolerance+1e-9
+	case schema.ShortText:
+		answer := r.Single()
+		if answer == "" {
+			return false
+		}
+		for _, accepted := range q.Answers {
+			if strings.EqualFold(answer, strings.TrimSpace(accepted)) {
+				return true
+			}
+		}
+		if q.Pattern != "" {
+			re, err := CompilePattern(q.Pattern)
+			return err == nil && re.MatchString(answer)
+		}
+		return false
+	case schema.Ordering, schema.Matching:
+		return slices.EqualFunc(r, q.Answers, func(a, b string) bool {
+			return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+		})
+	}
+	return false
+}
+
+// Credit returns the fraction of the question answered correctly, from 0 to 1
+// With partial credit every correct pick of a multi-select question earns its share of the question
+// and every wrong pick takes as much off, the other questions are either right or wrong
+func Credit(q *schema.Question, r Response, partial bool) float64 {
+	if Correct(q, r) {
+		return 1
+	}
+	if !partial || Type(q) != schema.MultiSelect || len(q.Answers) == 0 {
+		return 0
+	}
+	answers := normalize(q.Answers)
+	picks := 0
+	for _, pick := range normalize(r) {
+		if _, found := slices.BinarySearch(answers, pick); found {
+			picks++
+		} else {
+			picks--
+		}
+	}
+	return max(float64(picks)/float64(len(answers)), 0)
+}
+
+// Result is the grade of the answer to one question
+type Result struct {
+	Credit float64
+	Points float64 // points earned, negative for a wrong answer with negative marking
+}
+
+// Correct reports if the question got full credit
+func (r Result) Correct() bool {
+	return r.Credit == 1
+}
+
+// Grade is the grade of a quiz
+type Grade struct {
+	Results  []Result // Results[i] is the grade of the i-th question
+	Score    float64  // never below 0, even with negative marking
+	MaxScore float64
+}
+
+// GradeQuiz grades the responses to the questions of a quiz with the rules of the quiz
+// responses[i] is the answer to questions[i], missing responses are left unanswered
+// Unanswered questions earn nothing but are not marked negatively
+func GradeQuiz(questions []schema.QuizQuestion, responses []Response, rules schema.ScoringRules) Grade {
+	grade := Grade{Results: make([]Result, len(questions))}
+	for i, qq := range questions {
+		var r Response
+		if i < len(responses) {
+			r = responses[i]
+		}
+		result := Result{Credit: Credit(&qq.Question, r, rules.PartialCredit)}
+		result.Points = result.Credit * qq.Points
+		if result.Credit == 0 && !r.Blank() {
+			result.Points = -rules.NegativeMarking * qq.Points
+		}
+		grade.Results[i] = result
+		grade.Score += result.Points
+		grade.MaxScore += qq.Points
+	}
+	grade.Score = max(grade.Score, 0)
+	return grade
+}
+
+// Passed reports if the score reaches the passing score of the rules, nil if there is none
+func Passed(rules schema.ScoringRules, score, maxScore float64) *bool {
+	if rules.PassingScore == 0 {
+		return nil
+	}
+	passed := maxScore > 0 && score >= rules.PassingScore*maxScore-1e-9
+	return &passed
+}
+
+// CompilePattern compiles the regex of a short text question, it has to match the whole answer
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`(?i)^(?:` + pattern + `)$`)
+}
+
+// SameSet reports if a and b hold the same values, ignoring order, case and duplicates
+func SameSet(a, b []string) bool {
+	return slices.Equal(normalize(a), normalize(b))
+}
+
+// normalize returns the values lowercased, trimmed, sorted and without duplicates
+func normalize(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(strings.TrimSpace(v))
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
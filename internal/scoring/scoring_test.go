@@ -0,0 +1,122 @@
+package scoring
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+func TestCorrect_QuestionTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		question schema.Question
+		answer   Response
+		want     bool
+	}{
+		{"single choice", schema.Question{Options: []string{"a", "b"}, Answer: "b"}, Response{" B "}, true},
+		{"multi select", schema.Question{Type: schema.MultiSelect, Answers: []string{"a", "c"}}, Response{"c", "a"}, true},
+		{"multi select missing option", schema.Question{Type: schema.MultiSelect, Answers: []string{"a", "c"}}, Response{"a"}, false},
+		{"true false", schema.Question{Type: schema.TrueFalse, Answer: "false"}, Response{"False"}, true},
+		{"numeric within tolerance", schema.Question{Type: schema.Numeric, Answer: "3.14", Tolerance: 0.01}, Response{"3.15"}, true},
+		{"numeric outside tolerance", schema.Question{Type: schema.Numeric, Answer: "3.14", Tolerance: 0.01}, Response{"3.2"}, false},
+		{"short text variant", schema.Question{Type: schema.ShortText, Answers: []string{"H2O", "water"}}, Response{"Water"}, true},
+		{"short text pattern", schema.Question{Type: schema.ShortText, Pattern: `colou?r`}, Response{"Color"}, true},
+		{"short text pattern matches whole answer", schema.Question{Type: schema.ShortText, Pattern: `colou?r`}, Response{"colorful"}, false},
+		{"ordering", schema.Question{Type: schema.Ordering, Answers: []string{"1", "2", "3"}}, Response{"1", "2", "3"}, true},
+		{"ordering wrong order", schema.Question{Type: schema.Ordering, Answers: []string{"1", "2", "3"}}, Response{"2", "1", "3"}, false},
+		{"matching", schema.Question{Type: schema.Matching, Options: []string{"France", "Spain"}, Answers: []string{"Paris", "Madrid"}}, Response{"Paris", "Madrid"}, true},
+		{"no answer", schema.Question{Type: schema.Numeric, Answer: "0"}, nil, false},
+	}
+	for _, tt := range tests {
+		if got := Correct(&tt.question, tt.answer); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCredit_PartialMultiSelect(t *testing.T) {
+	q := schema.Question{Type: schema.MultiSelect, Options: []string{"a", "b", "c", "d"}, Answers: []string{"a", "b", "c"}}
+	tests := []struct {
+		name    string
+		answer  Response
+		partial bool
+		want    float64
+	}{
+		{"all correct", Response{"a", "b", "c"}, true, 1},
+		{"two of three", Response{"a", "b"}, true, 2.0 / 3},
+		{"wrong pick cancels a correct one", Response{"a", "b", "d"}, true, 1.0 / 3},
+		{"never below zero", Response{"d"}, true, 0},
+		{"without partial credit", Response{"a", "b"}, false, 0},
+	}
+	for _, tt := range tests {
+		if got := Credit(&q, tt.answer, tt.partial); got != tt.want {
+			t.Errorf("%s: expected credit %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGradeQuiz(t *testing.T) {
+	questions := []schema.QuizQuestion{
+		{Position: 0, Points: 1, Question: schema.Question{Options: []string{"a", "b"}, Answer: "a"}},
+		{Position: 1, Points: 2, Question: schema.Question{Type: schema.MultiSelect, Options: []string{"a", "b", "c"}, Answers: []string{"a", "b"}}},
+		{Position: 2, Points: 4, Question: schema.Question{Type: schema.Numeric, Answer: "10"}},
+	}
+	tests := []struct {
+		name      string
+		responses []Response
+		rules     schema.ScoringRules
+		want      []float64
+		wantScore float64
+	}{
+		{"right or wrong", []Response{{"a"}, {"a"}, {"10"}}, schema.ScoringRules{}, []float64{1, 0, 4}, 5},
+		{"partial credit", []Response{{"a"}, {"a"}, {"10"}}, schema.ScoringRules{PartialCredit: true}, []float64{1, 1, 4}, 6},
+		{"negative marking", []Response{{"b"}, {"a", "b"}, {"9"}}, schema.ScoringRules{NegativeMarking: 0.25}, []float64{-0.25, 2, -1}, 0.75},
+		{"unanswered is not marked negatively", []Response{{"a"}, nil}, schema.ScoringRules{NegativeMarking: 0.5}, []float64{1, 0, 0}, 1},
+		{"score never below zero", []Response{{"b"}, {"c"}, {"1"}}, schema.ScoringRules{NegativeMarking: 1}, []float64{-1, -2, -4}, 0},
+	}
+	for _, tt := range tests {
+		grade := GradeQuiz(questions, tt.responses, tt.rules)
+		points := make([]float64, len(grade.Results))
+		for i, r := range grade.Results {
+			points[i] = r.Points
+		}
+		if !slices.Equal(points, tt.want) || grade.Score != tt.wantScore || grade.MaxScore != 7 {
+			t.Errorf("%s: expected points %v and score %v/7, got %v and %v/%v", tt.name, tt.want, tt.wantScore, points, grade.Score, grade.MaxScore)
+		}
+	}
+}
+
+func TestPassed(t *testing.T) {
+	if Passed(schema.ScoringRules{}, 0, 10) != nil {
+		t.Errorf("expected no verdict without a passing score")
+	}
+	rules := schema.ScoringRules{PassingScore: 0.6}
+	if passed := Passed(rules, 6, 10); passed == nil || !*passed {
+		t.Errorf("expected 6/10 to pass with a passing score of 60%%")
+	}
+	if passed := Passed(rules, 5.9, 10); passed == nil || *passed {
+		t.Errorf("expected 5.9/10 to fail with a passing score of 60%%")
+	}
+}
+
+func TestResponse_UnmarshalJSON(t *testing.T) {
+	var responses []Response
+	if err := json.Unmarshal([]byte(`["Paris", 3.5, ["a", "b"], null, true, false]`), &responses); err != nil {
+		t.Fatalf("failed to unmarshal responses: %v", err)
+	}
+	want := []Response{{"Paris"}, {"3.5"}, {"a", "b"}, nil, {"true"}, {"false"}}
+	if !slices.EqualFunc(responses, want, slices.Equal) {
+		t.Errorf("expected %v, got %v", want, responses)
+	}
+	if err := json.Unmarshal([]byte(`[{"a": 1}]`), &responses); err == nil {
+		t.Errorf("expected an object to be rejected")
+	}
+
+	question := schema.Question{Type: schema.TrueFalse, Answer: "true", Options: []string{"true", "false"}}
+	var answer Response
+	if err := json.Unmarshal([]byte(`true`), &answer); err != nil || !Correct(&question, answer) {
+		t.Errorf("expected the boolean true to answer a true/false question, got %v %v", answer, err)
+	}
+}
@@ -1,17 +1,17 @@
 package store
 
 import (
-	"errors"
+	"context"
 
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
 )
 
 type CourseStoreInterface interface {
-	ListCourses(limit, offset int) ([]schema.Course, error)
-	CreateCourse(course *schema.Course) error
-	GetCourseById(id uint) (*schema.Course, error)
-	DeleteCourse(course *schema.Course) error
-	UpdateCourse(course *schema.Course) error
+	ListCourses(ctx context.Context, limit, offset int) ([]schema.Course, error)
+	CreateCourse(ctx context.Context, course *schema.Course) error
+	GetCourseById(ctx context.Context, id uint) (*schema.Course, error)
+	DeleteCourse(ctx context.Context, course *schema.Course) error
+	UpdateCourse(ctx context.Context, course *schema.Course) error
 }
 
 type CourseStore struct {
@@ -22,49 +22,58 @@ func NewCourseStore(store *Store) *CourseStore {
 	return &CourseStore{Store: store}
 }
 
-func (s *CourseStore) CreateCourse(course *schema.Course) error {
-	if err := s.db.Create(course).Error; err != nil {
-		s.logger.Error("Failed to create course", err)
-		return errors.New("failed to create course")
+func (s *CourseStore) CreateCourse(ctx context.Context, course *schema.Course) error {
+	ctx, span := tracer.Start(ctx, "CourseStore.CreateCourse")
+	defer span.End()
+
+	if err := s.db.WithContext(ctx).Create(course).Error; err != nil {
+		return s.wrapError(ctx, err, "failed to create course")
 	}
 	return nil
 }
 
-func (s *CourseStore) ListCourses(limit, offset int) ([]schema.Course, error) {
+func (s *CourseStore) ListCourses(ctx context.Context, limit, offset int) ([]schema.Course, error) {
+	ctx, span := tracer.Start(ctx, "CourseStore.ListCourses")
+	defer span.End()
+
 	var courses []schema.Course
 
-	if err := s.db.Order("created_at desc").Limit(limit).Offset(offset).Find(&courses).Error; err != nil {
-		s.logger.Error("Failed to list courses", err)
-		return nil, errors.New("database error")
+	if err := s.db.WithContext(ctx).Order("created_at desc").Limit(limit).Offset(offset).Find(&courses).Error; err != nil {
+		return nil, s.wrapError(ctx, err, "failed to list courses")
 	}
 
 	return courses, nil
 }
 
-func (s *CourseStore) GetCourseById(id uint) (*schema.Course, error) {
+func (s *CourseStore) GetCourseById(ctx context.Context, id uint) (*schema.Course, error) {
+	ctx, span := tracer.Start(ctx, "CourseStore.GetCourseById")
+	defer span.End()
+
 	var course schema.Course
 
-	if err := s.db.Where("id = ?", id).First(&course).Error; err != nil {
-		s.logger.Error("Failed to get course", err)
-		return &course, errors.New("failed to get course")
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&course).Error; err != nil {
+		return nil, s.wrapError(ctx, err, "failed to get course")
 	}
 
 	return &course, nil
 }
 
-// shouldnt allow to delete someone else course
-func (s *CourseStore) DeleteCourse(course *schema.Course) error {
-	if err := s.db.Delete(course).Error; err != nil {
-		s.logger.Error("Failed to delete course", err)
-		return errors.New("failed to delete course")
+func (s *CourseStore) DeleteCourse(ctx context.Context, course *schema.Course) error {
+	ctx, span := tracer.Start(ctx, "CourseStore.DeleteCourse")
+	defer span.End()
+
+	if err := s.db.WithContext(ctx).Delete(course).Error; err != nil {
+		return s.wrapError(ctx, err, "failed to delete course")
 	}
 	return nil
 }
 
-func (s *CourseStore) UpdateCourse(course *schema.Course) error {
-	if err := s.db.Save(course).Error; err != nil {
-		s.logger.Error("Failed to update course", err)
-		return errors.New("failed to update course")
+func (s *CourseStore) UpdateCourse(ctx context.Context, course *schema.Course) error {
+	ctx, span := tracer.Start(ctx, "CourseStore.UpdateCourse")
+	defer span.End()
+
+	if err := s.db.WithContext(ctx).Save(course).Error; err != nil {
+		return s.wrapError(ctx, err, "failed to update course")
 	}
 	return nil
 }
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+)
+
+type EnrollmentStoreInterface interface {
+	GetEnrollment(ctx context.Context, userID, courseID uint) (*schema.Enrollment, error)
+	SaveEnrollment(ctx context.Context, enrollment *schema.Enrollment) error
+	ListEnrollments(ctx context.Context, filter EnrollmentFilter, limit, offset int) ([]schema.Enrollment, error)
+}
+
+// EnrollmentFilter narrows down the enrollments returned by ListEnrollments
+// Zero values are ignored
+type EnrollmentFilter struct {
+	UserID   uint
+	CourseID uint
+	Status   schema.EnrollmentStatus
+}
+
+type EnrollmentStore struct {
+	*Store
+}
+
+func NewEnrollmentStore(store *Store) *EnrollmentStore {
+	return &EnrollmentStore{Store: store}
+}
+
+// GetEnrollment returns the enrollment of the user in the course
+// It returns nil if the user never enrolled
+func (es *EnrollmentStore) GetEnrollment(ctx context.Context, userID, courseID uint) (*schema.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "EnrollmentStore.GetEnrollment")
+	defer span.End()
+
+	var enrollments []schema.Enrollment
+
+	err := es.db.WithContext(ctx).Where("user_id = ? AND course_id = ?", userID, courseID).Limit(1).Find(&enrollments).Error
+	if err != nil {
+		return nil, es.wrapError(ctx, err, "failed to get enrollment")
+	}
+	if len(enrollments) == 0 {
+		return nil, nil
+	}
+	return &enrollments[0], nil
+}
+
+// SaveEnrollment creates the enrollment or updates its status
+func (es *EnrollmentStore) SaveEnrollment(ctx context.Context, enrollment *schema.Enrollment) error {
+	ctx, span := tracer.Start(ctx, "EnrollmentStore.SaveEnrollment")
+	defer span.End()
+
+	if err := es.db.WithContext(ctx).Omit("User", "Course").Save(enrollment).Error; err != nil {
+		return es.wrapError(ctx, err, "failed to save enrollment")
+	}
+	return nil
+}
+
+func (es *EnrollmentStore) ListEnrollments(ctx context.Context, filter EnrollmentFilter, limit, offset int) ([]schema.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "EnrollmentStore.ListEnrollments")
+	defer span.End()
+
+	var enrollments []schema.Enrollment
+
+	query := es.db.WithContext(ctx).Preload("User").Preload("Course")
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Order("created_at").Limit(limit).Offset(offset).Find(&enrollments).Error; err != nil {
+		return nil, es.wrapError(ctx, err, "failed to list enrollments")
+	}
+	return enrollments, nil
+}
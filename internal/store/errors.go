@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// Sentinel errors returned by the stores, wrapped with some context
+// Handlers use errors.Is to pick the HTTP status, any other error is a database failure
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+)
+
+// wrapError logs unexpected database errors and translates the expected ones to the sentinel errors
+// msg describes the failed operation, e.g. "failed to get course"
+// Unexpected errors also fail the span of the store call in ctx
+func (s *Store) wrapError(ctx context.Context, err error, msg string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%s: %w", msg, ErrNotFound)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%s: %w", msg, ErrConflict)
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrConflict), errors.Is(err, ErrValidation):
+		return err
+	}
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, msg)
+	s.logger.WithContext(ctx).Errorf("%s: %v", msg, err)
+	return errors.New(msg)
+}
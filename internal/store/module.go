@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidOrder is returned when a reorder does not list every item exactly once
+var ErrInvalidOrder = fmt.Errorf("%w: the new order must list every item exactly once", ErrValidation)
+
+// ModuleStoreInterface handles the modules of a course and the lessons inside them
+type ModuleStoreInterface interface {
+	ListModules(ctx context.Context, courseID uint) ([]schema.Module, error)
+	GetModuleById(ctx context.Context, id uint) (*schema.Module, error)
+	CreateModule(ctx context.Context, module *schema.Module) error
+	UpdateModule(ctx context.Context, module *schema.Module) error
+	DeleteModule(ctx context.Context, module *schema.Module) error
+	ReorderModules(ctx context.Context, courseID uint, ids []uint) error
+
+	GetLessonById(ctx context.Context, id uint) (*schema.Lesson, error)
+	CreateLesson(ctx context.Context, lesson *schema.Lesson) error
+	UpdateLesson(ctx context.Context, lesson *schema.Lesson) error
+	DeleteLesson(ctx context.Context, lesson *schema.Lesson) error
+	ReorderLessons(ctx context.Context, moduleID uint, ids []uint) error
+}
+
+type ModuleStore struct {
+	*Store
+}
+
+func NewModuleStore(store *Store) *ModuleStore {
+	return &ModuleStore{Store: store}
+}
+
+// ListModules returns the outline of a course, modules and their lessons in order
+func (ms *ModuleStore) ListModules(ctx context.Context, courseID uint) ([]schema.Module, error) {
+	ctx, span := tracer.Start(ctx, "ModuleStore.ListModules")
+	defer span.End()
+
+	var modules []schema.Module
+
+	err := ms.db.WithContext(ctx).Preload("Lessons", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("course_id = ?", courseID).Order("position, id").Find(&modules).Error
+	if err != nil {
+		return nil, ms.wrapError(ctx, err, "failed to list modules")
+	}
+	return modules, nil
+}
+
+func (ms *ModuleStore) GetModuleById(ctx context.Context, id uint) (*schema.Module, error) {
+	ctx, span := tracer.Start(ctx, "ModuleStore.GetModuleById")
+	defer span.End()
+
+	var module schema.Module
+
+	err := ms.db.WithContext(ctx).Preload("Lessons", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Where("id = ?", id).First(&module).Error
+	if err != nil {
+		return nil, ms.wrapError(ctx, err, "failed to get module")
+	}
+	return &module, nil
+}
+
+// CreateModule adds the module at the end of the course
+func (ms *ModuleStore) CreateModule(ctx context.Context, module *schema.Module) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.CreateModule")
+	defer span.End()
+
+	err := ms.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx, &schema.Module{}, "course_id", module.CourseID)
+		if err != nil {
+			return err
+		}
+		module.Position = position
+		return tx.Omit("Course").Create(module).Error
+	})
+	if err != nil {
+		return ms.wrapError(ctx, err, "failed to create module")
+	}
+	return nil
+}
+
+func (ms *ModuleStore) UpdateModule(ctx context.Context, module *schema.Module) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.UpdateModule")
+	defer span.End()
+
+	if err := ms.db.WithContext(ctx).Model(module).Update("title", module.Title).Error; err != nil {
+		return ms.wrapError(ctx, err, "failed to update module")
+	}
+	return nil
+}
+
+// DeleteModule deletes the module and its lessons, quizzes of those lessons are detached
+func (ms *ModuleStore) DeleteModule(ctx context.Context, module *schema.Module) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.DeleteModule")
+	defer span.End()
+
+	err := ms.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lessons := tx.Model(&schema.Lesson{}).Select("id").Where("module_id = ?", module.ID)
+		if err := tx.Model(&schema.Quiz{}).Where("lesson_id IN (?)", lessons).Update("lesson_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Select("Lessons").Delete(module).Error
+	})
+	if err != nil {
+		return ms.wrapError(ctx, err, "failed to delete module")
+	}
+	return nil
+}
+
+// ReorderModules sets the position of every module of the course to its index in ids
+func (ms *ModuleStore) ReorderModules(ctx context.Context, courseID uint, ids []uint) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.ReorderModules")
+	defer span.End()
+
+	return ms.reorder(ctx, &schema.Module{}, "course_id", courseID, ids)
+}
+
+func (ms *ModuleStore) GetLessonById(ctx context.Context, id uint) (*schema.Lesson, error) {
+	ctx, span := tracer.Start(ctx, "ModuleStore.GetLessonById")
+	defer span.End()
+
+	var lesson schema.Lesson
+
+	if err := ms.db.WithContext(ctx).Preload("Module").Where("id = ?", id).First(&lesson).Error; err != nil {
+		return nil, ms.wrapError(ctx, err, "failed to get lesson")
+	}
+	return &lesson, nil
+}
+
+// CreateLesson adds the lesson at the end of its module
+func (ms *ModuleStore) CreateLesson(ctx context.Context, lesson *schema.Lesson) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.CreateLesson")
+	defer span.End()
+
+	err := ms.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx, &schema.Lesson{}, "module_id", lesson.ModuleID)
+		if err != nil {
+			return err
+		}
+		lesson.Position = position
+		return tx.Omit("Module").Create(lesson).Error
+	})
+	if err != nil {
+		return ms.wrapError(ctx, err, "failed to create lesson")
+	}
+	return nil
+}
+
+func (ms *ModuleStore) UpdateLesson(ctx context.Context, lesson *schema.Lesson) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.UpdateLesson")
+	defer span.End()
+
+	err := ms.db.WithContext(ctx).Model(lesson).Select("title", "body", "estimated_minutes").Updates(lesson).Error
+	if err != nil {
+		return ms.wrapError(ctx, err, "failed to update lesson")
+	}
+	return nil
+}
+
+// DeleteLesson deletes the lesson, quizzes attached to it are detached
+func (ms *ModuleStore) DeleteLesson(ctx context.Context, lesson *schema.Lesson) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.DeleteLesson")
+	defer span.End()
+
+	err := ms.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&schema.Quiz{}).Where("lesson_id = ?", lesson.ID).Update("lesson_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(lesson).Error
+	})
+	if err != nil {
+		return ms.wrapError(ctx, err, "failed to delete lesson")
+	}
+	return nil
+}
+
+// ReorderLessons sets the position of every lesson of the module to its index in ids
+func (ms *ModuleStore) ReorderLessons(ctx context.Context, moduleID uint, ids []uint) error {
+	ctx, span := tracer.Start(ctx, "ModuleStore.ReorderLessons")
+	defer span.End()
+
+	return ms.reorder(ctx, &schema.Lesson{}, "module_id", moduleID, ids)
+}
+
+// nextPosition returns the position after the last of the rows owned by parentID
+// Deleting a row leaves a gap, so the rows are not counted
+func nextPosition(tx *gorm.DB, model any, parentColumn string, parentID uint) (int, error) {
+	var position int
+	err := tx.Model(model).Where(parentColumn+" = ?", parentID).Select("COALESCE(MAX(position), -1) + 1").Scan(&position).Error
+	return position, err
+}
+
+// reorder rewrites the positions of the rows owned by parentID
+// ids has to be a permutation of the ids of those rows
+func (ms *ModuleStore) reorder(ctx context.Context, model any, parentColumn string, parentID uint, ids []uint) error {
+	err := ms.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(model).Where(parentColumn+" = ?", parentID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		sorted := slices.Clone(ids)
+		slices.Sort(sorted)
+		slices.Sort(existing)
+		if !slices.Equal(sorted, existing) {
+			return ErrInvalidOrder
+		}
+		for position, id := range ids {
+			if err := tx.Model(model).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ms.wrapError(ctx, err, "failed to reorder")
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"gorm.io/gorm"
+)
+
+type QuestionStoreInterface interface {
+	CreateQuestion(ctx context.Context, question *schema.Question) error
+	CreateQuestions(ctx context.Context, questions []schema.Question) error
+	GetQuestionById(ctx context.Context, id uint) (*schema.Question, error)
+	ListQuestions(ctx context.Context, filter QuestionFilter, limit, offset int) ([]schema.Question, error)
+	UpdateQuestion(ctx context.Context, question *schema.Question) error
+	DeleteQuestion(ctx context.Context, question *schema.Question) error
+}
+
+// QuestionFilter narrows down the questions returned by ListQuestions
+// Zero values are ignored
+type QuestionFilter struct {
+	CourseID   uint
+	Tag        string
+	Difficulty schema.Difficulty
+	Type       schema.QuestionType
+}
+
+type QuestionStore struct {
+	*Store
+}
+
+func NewQuestionStore(store *Store) *QuestionStore {
+	return &QuestionStore{Store: store}
+}
+
+func (qs *QuestionStore) CreateQuestion(ctx context.Context, question *schema.Question) error {
+	ctx, span := tracer.Start(ctx, "QuestionStore.CreateQuestion")
+	defer span.End()
+
+	if err := qs.db.WithContext(ctx).Create(question).Error; err != nil {
+		return qs.wrapError(ctx, err, "failed to create question")
+	}
+	return nil
+}
+
+// CreateQuestions adds all the questions to the bank or none of them
+func (qs *QuestionStore) CreateQuestions(ctx context.Context, questions []schema.Question) error {
+	ctx, span := tracer.Start(ctx, "QuestionStore.CreateQuestions")
+	defer span.End()
+
+	err := qs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range questions {
+			if err := tx.Create(&questions[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return qs.wrapError(ctx, err, "failed to create questions")
+	}
+	return nil
+}
+
+// findOrCreateQuestion replaces the question with an identical question of the bank of its course,
+// or adds it to the bank if there is none, so generating quizzes does not fill the bank with copies
+func findOrCreateQuestion(tx *gorm.DB, question *schema.Question) error {
+	var candidates []schema.Question
+	err := tx.Preload("Tags").Where("course_id = ? AND type = ? AND question = ?", question.CourseID, question.Type, question.Question).
+		Order("id").Find(&candidates).Error
+	if err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		if sameQuestion(&candidate, question) {
+			*question = candidate
+			return nil
+		}
+	}
+	return tx.Create(question).Error
+}
+
+// sameQuestion reports if two questions of a course ask and grade the same thing, their tags aside
+func sameQuestion(a, b *schema.Question) bool {
+	return a.Answer == b.Answer && a.Tolerance == b.Tolerance && a.Pattern == b.Pattern && a.Difficulty == b.Difficulty &&
+		slices.Equal(a.Options, b.Options) && slices.Equal(a.Answers, b.Answers) && slices.Equal(a.Matches, b.Matches)
+}
+
+func (qs *QuestionStore) GetQuestionById(ctx context.Context, id uint) (*schema.Question, error) {
+	ctx, span := tracer.Start(ctx, "QuestionStore.GetQuestionById")
+	defer span.End()
+
+	var question schema.Question
+
+	if err := qs.db.WithContext(ctx).Preload("Tags").Where("id = ?", id).First(&question).Error; err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to get question")
+	}
+
+	return &question, nil
+}
+
+func (qs *QuestionStore) ListQuestions(ctx context.Context, filter QuestionFilter, limit, offset int) ([]schema.Question, error) {
+	ctx, span := tracer.Start(ctx, "QuestionStore.ListQuestions")
+	defer span.End()
+
+	var questions []schema.Question
+
+	query := qs.db.WithContext(ctx).Preload("Tags")
+	if filter.CourseID != 0 {
+		query = query.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.Difficulty != "" {
+		query = query.Where("difficulty = ?", filter.Difficulty)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", qs.db.Model(&schema.QuestionTag{}).Select("question_id").Where("name = ?", filter.Tag))
+	}
+
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&questions).Error; err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to list questions")
+	}
+	return questions, nil
+}
+
+// UpdateQuestion saves the question and replaces its tags
+// Questions used by a quiz can not be changed, the quiz would change along, ErrQuestionInUse is returned
+func (qs *QuestionStore) UpdateQuestion(ctx context.Context, question *schema.Question) error {
+	ctx, span := tracer.Start(ctx, "QuestionStore.UpdateQuestion")
+	defer span.End()
+
+	err := qs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkQuestionUnused(tx, question.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("question_id = ?", question.ID).Delete(&schema.QuestionTag{}).Error; err != nil {
+			return err
+		}
+		for i := range question.Tags {
+			question.Tags[i].ID = 0
+		}
+		return tx.Omit("Course").Save(question).Error
+	})
+	if err != nil {
+		return qs.wrapError(ctx, err, "failed to update question")
+	}
+	return nil
+}
+
+// ErrQuestionInUse is returned when changing or deleting a question which quizzes were generated with
+var ErrQuestionInUse = fmt.Errorf("%w: the question is used by a quiz", ErrConflict)
+
+// checkQuestionUnused returns ErrQuestionInUse if a quiz uses the question
+func checkQuestionUnused(tx *gorm.DB, id uint) error {
+	var used int64
+	if err := tx.Model(&schema.QuizQuestion{}).Where("question_id = ?", id).Count(&used).Error; err != nil {
+		return err
+	}
+	if used > 0 {
+		return ErrQuestionInUse
+	}
+	return nil
+}
+
+// DeleteQuestion removes the question from the bank
+// Questions used by a quiz can not be deleted, ErrQuestionInUse is returned
+func (qs *QuestionStore) DeleteQuestion(ctx context.Context, question *schema.Question) error {
+	ctx, span := tracer.Start(ctx, "QuestionStore.DeleteQuestion")
+	defer span.End()
+
+	err := qs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkQuestionUnused(tx, question.ID); err != nil {
+			return err
+		}
+		return tx.Select("Tags").Delete(question).Error
+	})
+	if err != nil {
+		return qs.wrapError(ctx, err, "failed to delete question")
+	}
+	return nil
+}
@@ -1,15 +1,35 @@
 package store
 
 import (
-	"errors"
+	"context"
+	"time"
 
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
+	"gorm.io/gorm"
 )
 
 type QuizStoreInterface interface {
-	CreateQuiz(quiz *schema.Quiz) error
-	GetQuizById(id uint) (*schema.Quiz, error)
-	RegisterQuizTaken(user *schema.User, quiz *schema.Quiz) error
+	CreateQuiz(ctx context.Context, quiz *schema.Quiz) error
+	GetQuizById(ctx context.Context, id uint) (*schema.Quiz, error)
+	ListLessonQuizIDs(ctx context.Context, lessonID uint) ([]uint, error)
+	ListCourseQuizzes(ctx context.Context, courseID uint) ([]schema.Quiz, error)
+	UpdateQuizSettings(ctx context.Context, quiz *schema.Quiz) error
+	UpdateQuizPoints(ctx context.Context, quiz *schema.Quiz) error
+	StartAttempt(ctx context.Context, attempt *schema.QuizAttempt) error
+	GetOpenAttempt(ctx context.Context, userID, quizID uint) (*schema.QuizAttempt, error)
+	UpdateAttempt(ctx context.Context, attempt *schema.QuizAttempt) error
+	SubmitAttempt(ctx context.Context, attempt *schema.QuizAttempt) error
+	CountSubmittedAttempts(ctx context.Context, userID, quizID uint) (int64, error)
+	ListAttempts(ctx context.Context, filter AttemptFilter, limit, offset int) ([]schema.QuizAttempt, error)
+	ListItemResponses(ctx context.Context, courseID uint) ([]ItemResponse, error)
+}
+
+// AttemptFilter narrows down the attempts returned by ListAttempts
+// Zero values are ignored
+type AttemptFilter struct {
+	UserID   uint
+	CourseID uint
+	QuizID   uint
 }
 type QuizStore struct {
 	*Store
@@ -19,28 +39,264 @@ func NewQuizStore(store *Store) *QuizStore {
 	return &QuizStore{Store: store}
 }
 
-func (qs *QuizStore) CreateQuiz(quiz *schema.Quiz) error {
-	if err := qs.db.Create(quiz).Error; err != nil {
-		qs.logger.Error("Failed to create quiz", err)
-		return errors.New("failed to create quiz")
+// CreateQuiz saves the quiz along with the positions of its questions
+// Questions without an id are new, they are added to the bank unless an identical question is there already.
+// Either all of it is saved or nothing
+func (qs *QuizStore) CreateQuiz(ctx context.Context, quiz *schema.Quiz) error {
+	ctx, span := tracer.Start(ctx, "QuizStore.CreateQuiz")
+	defer span.End()
+
+	err := qs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range quiz.Questions {
+			if quiz.Questions[i].Question.ID != 0 {
+				continue
+			}
+			if err := findOrCreateQuestion(tx, &quiz.Questions[i].Question); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Questions").Create(quiz).Error; err != nil {
+			return err
+		}
+		for i := range quiz.Questions {
+			quiz.Questions[i].QuizID = quiz.ID
+			quiz.Questions[i].QuestionID = quiz.Questions[i].Question.ID
+		}
+		if len(quiz.Questions) == 0 {
+			return nil
+		}
+		return tx.Omit("Question").Create(&quiz.Questions).Error
+	})
+	if err != nil {
+		return qs.wrapError(ctx, err, "failed to create quiz")
 	}
+	applyOptionOrder(quiz)
 	return nil
 }
 
-func (qs *QuizStore) GetQuizById(id uint) (*schema.Quiz, error) {
+// GetQuizById returns the quiz with its questions in order
+func (qs *QuizStore) GetQuizById(ctx context.Context, id uint) (*schema.Quiz, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.GetQuizById")
+	defer span.End()
+
 	var quiz schema.Quiz
 
-	if err := qs.db.Where("id = ?", id).First(&quiz).Error; err != nil {
-		qs.logger.Error("Failed to get quiz", err)
-		return &quiz, errors.New("failed to get quiz")
+	err := qs.db.WithContext(ctx).Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Questions.Question").Where("id = ?", id).First(&quiz).Error
+	if err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to get quiz")
 	}
+	applyOptionOrder(&quiz)
 
 	return &quiz, nil
 }
-func (qs *QuizStore) RegisterQuizTaken(user *schema.User, quiz *schema.Quiz) error {
-	if err := qs.db.Create(&schema.QuizzesTaken{User: *user, Quiz: *quiz}).Error; err != nil {
-		qs.logger.Error("Failed to take quiz", err)
-		return errors.New("failed to take quiz")
+
+// applyOptionOrder puts the options of the questions in the order of the quiz, the bank keeps its own
+func applyOptionOrder(quiz *schema.Quiz) {
+	for i := range quiz.Questions {
+		qq := &quiz.Questions[i]
+		if len(qq.Options) > 0 {
+			qq.Question.Options = qq.Options
+		}
+		if len(qq.Matches) > 0 {
+			qq.Question.Matches = qq.Matches
+		}
+	}
+}
+
+// ListLessonQuizIDs returns the ids of the quizzes attached to the lesson
+func (qs *QuizStore) ListLessonQuizIDs(ctx context.Context, lessonID uint) ([]uint, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.ListLessonQuizIDs")
+	defer span.End()
+
+	ids := []uint{}
+
+	if err := qs.db.WithContext(ctx).Model(&schema.Quiz{}).Where("lesson_id = ?", lessonID).Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to list quizzes of lesson")
+	}
+	return ids, nil
+}
+
+// ListCourseQuizzes returns the quizzes of the course, oldest first
+// Only the positions and points of their questions are loaded, not the questions themselves
+func (qs *QuizStore) ListCourseQuizzes(ctx context.Context, courseID uint) ([]schema.Quiz, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.ListCourseQuizzes")
+	defer span.End()
+
+	var quizzes []schema.Quiz
+
+	err := qs.db.WithContext(ctx).Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("course_id = ?", courseID).Order("id").Find(&quizzes).Error
+	if err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to list quizzes of course")
+	}
+	return quizzes, nil
+}
+
+// UpdateQuizSettings saves the settings of the quiz, leaving its questions untouched
+func (qs *QuizStore) UpdateQuizSettings(ctx context.Context, quiz *schema.Quiz) error {
+	ctx, span := tracer.Start(ctx, "QuizStore.UpdateQuizSettings")
+	defer span.End()
+
+	err := qs.db.WithContext(ctx).Model(&schema.Quiz{ID: quiz.ID}).
+		Select("opens_at", "closes_at", "time_limit_minutes", "max_attempts", "late_policy", "late_penalty",
+			"partial_credit", "negative_marking", "passing_score").
+		Updates(quiz).Error
+	if err != nil {
+		return qs.wrapError(ctx, err, "failed to update quiz settings")
 	}
 	return nil
 }
+
+// UpdateQuizPoints saves the points of every question of the quiz
+func (qs *QuizStore) UpdateQuizPoints(ctx context.Context, quiz *schema.Quiz) error {
+	ctx, span := tracer.Start(ctx, "QuizStore.UpdateQuizPoints")
+	defer span.End()
+
+	err := qs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, qq := range quiz.Questions {
+			err := tx.Model(&schema.QuizQuestion{}).Where("quiz_id = ? AND position = ?", quiz.ID, qq.Position).
+				Update("points", qq.Points).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return qs.wrapError(ctx, err, "failed to update quiz points")
+	}
+	return nil
+}
+
+// StartAttempt records that the user has started taking the quiz
+func (qs *QuizStore) StartAttempt(ctx context.Context, attempt *schema.QuizAttempt) error {
+	ctx, span := tracer.Start(ctx, "QuizStore.StartAttempt")
+	defer span.End()
+
+	if attempt.StartedAt.IsZero() {
+		attempt.StartedAt = time.Now()
+	}
+	if err := qs.db.WithContext(ctx).Omit("User", "Quiz").Create(attempt).Error; err != nil {
+		return qs.wrapError(ctx, err, "failed to start attempt")
+	}
+	return nil
+}
+
+// GetOpenAttempt returns the latest attempt of the user which is not submitted yet, with the answers given so far
+// It returns nil if the user has no attempt in progress
+func (qs *QuizStore) GetOpenAttempt(ctx context.Context, userID, quizID uint) (*schema.QuizAttempt, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.GetOpenAttempt")
+	defer span.End()
+
+	var attempts []schema.QuizAttempt
+
+	err := qs.db.WithContext(ctx).Preload("Answers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Where("user_id = ? AND quiz_id = ? AND submitted_at IS NULL", userID, quizID).
+		Order("started_at desc").Limit(1).Find(&attempts).Error
+	if err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to get attempt")
+	}
+	if len(attempts) == 0 {
+		return nil, nil
+	}
+	return &attempts[0], nil
+}
+
+// UpdateAttempt saves an attempt in progress, adding its new answers
+func (qs *QuizStore) UpdateAttempt(ctx context.Context, attempt *schema.QuizAttempt) error {
+	ctx, span := tracer.Start(ctx, "QuizStore.UpdateAttempt")
+	defer span.End()
+
+	if err := qs.db.WithContext(ctx).Omit("User", "Quiz").Save(attempt).Error; err != nil {
+		return qs.wrapError(ctx, err, "failed to update attempt")
+	}
+	return nil
+}
+
+// SubmitAttempt saves the graded attempt along with its answers
+func (qs *QuizStore) SubmitAttempt(ctx context.Context, attempt *schema.QuizAttempt) error {
+	ctx, span := tracer.Start(ctx, "QuizStore.SubmitAttempt")
+	defer span.End()
+
+	if err := qs.db.WithContext(ctx).Omit("User", "Quiz").Save(attempt).Error; err != nil {
+		return qs.wrapError(ctx, err, "failed to submit attempt")
+	}
+	return nil
+}
+
+// CountSubmittedAttempts returns how many attempts of the quiz the user has submitted
+func (qs *QuizStore) CountSubmittedAttempts(ctx context.Context, userID, quizID uint) (int64, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.CountSubmittedAttempts")
+	defer span.End()
+
+	var count int64
+
+	err := qs.db.WithContext(ctx).Model(&schema.QuizAttempt{}).
+		Where("user_id = ? AND quiz_id = ? AND submitted_at IS NOT NULL", userID, quizID).Count(&count).Error
+	if err != nil {
+		return 0, qs.wrapError(ctx, err, "failed to count attempts")
+	}
+	return count, nil
+}
+
+// ListAttempts returns the submitted attempts matching the filter, latest first
+// The user, quiz and course of every attempt are loaded as well
+func (qs *QuizStore) ListAttempts(ctx context.Context, filter AttemptFilter, limit, offset int) ([]schema.QuizAttempt, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.ListAttempts")
+	defer span.End()
+
+	var attempts []schema.QuizAttempt
+
+	query := qs.db.WithContext(ctx).Model(&schema.QuizAttempt{}).
+		Preload("User").Preload("Quiz.Course").
+		Where("quiz_attempts.submitted_at IS NOT NULL")
+	if filter.UserID != 0 {
+		query = query.Where("quiz_attempts.user_id = ?", filter.UserID)
+	}
+	if filter.QuizID != 0 {
+		query = query.Where("quiz_attempts.quiz_id = ?", filter.QuizID)
+	}
+	if filter.CourseID != 0 {
+		query = query.Joins("JOIN quizzes ON quizzes.id = quiz_attempts.quiz_id").
+			Where("quizzes.course_id = ?", filter.CourseID)
+	}
+
+	err := query.Order("quiz_attempts.submitted_at desc").Limit(limit).Offset(offset).Find(&attempts).Error
+	if err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to list attempts")
+	}
+	return attempts, nil
+}
+
+// ItemResponse is a graded answer to a question of the bank, along with the score of its attempt
+type ItemResponse struct {
+	QuestionID uint
+	AttemptID  uint
+	Answer     string
+	Answers    []string `gorm:"serializer:json"`
+	Correct    bool
+	TimeSpent  *float64
+	Score      float64 // score of the attempt
+	MaxScore   float64 // max score of the attempt
+}
+
+// ListItemResponses returns the answers of every submitted attempt of the quizzes of the course
+func (qs *QuizStore) ListItemResponses(ctx context.Context, courseID uint) ([]ItemResponse, error) {
+	ctx, span := tracer.Start(ctx, "QuizStore.ListItemResponses")
+	defer span.End()
+
+	var responses []ItemResponse
+
+	err := qs.db.WithContext(ctx).Table("attempt_answers").
+		Select("quiz_questions.question_id, attempt_answers.attempt_id, attempt_answers.answer, attempt_answers.answers, "+
+			"attempt_answers.correct, attempt_answers.time_spent, quiz_attempts.score, quiz_attempts.max_score").
+		Joins("JOIN quiz_attempts ON quiz_attempts.id = attempt_answers.attempt_id").
+		Joins("JOIN quizzes ON quizzes.id = quiz_attempts.quiz_id").
+		Joins("JOIN quiz_questions ON quiz_questions.quiz_id = quiz_attempts.quiz_id AND quiz_questions.position = attempt_answers.position").
+		Where("quizzes.course_id = ? AND quiz_attempts.submitted_at IS NOT NULL", courseID).
+		Order("attempt_answers.id").Scan(&responses).Error
+	if err != nil {
+		return nil, qs.wrapError(ctx, err, "failed to list responses")
+	}
+	return responses, nil
+}
@@ -2,9 +2,13 @@ package store
 
 import (
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
 )
 
+// tracer opens a span for every call to a store, the queries it runs are traced as its children
+var tracer = otel.Tracer("github.com/rudransh-shrivastava/rudransh-backend-task/internal/store")
+
 type Store struct {
 	db     *gorm.DB
 	logger *logrus.Logger
@@ -2,14 +2,14 @@ package store
 
 import (
 	"context"
-	"errors"
 
 	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/schema"
 )
 
 type UserStoreInterface interface {
-	CreateUser(user *schema.User) error
-	GetUserByUID(uid string) (*schema.User, error)
+	CreateUser(ctx context.Context, user *schema.User) error
+	GetUserByUID(ctx context.Context, uid string) (*schema.User, error)
+	GetUserById(ctx context.Context, id uint) (*schema.User, error)
 	GetUserFromContext(ctx context.Context) (*schema.User, error)
 }
 
@@ -21,20 +21,37 @@ func NewUserStore(store *Store) *UserStore {
 	return &UserStore{Store: store}
 }
 
-func (us *UserStore) CreateUser(user *schema.User) error {
-	if err := us.db.Create(user).Error; err != nil {
-		us.logger.Error("Failed to create user", err)
-		return errors.New("failed to create user")
+func (us *UserStore) CreateUser(ctx context.Context, user *schema.User) error {
+	ctx, span := tracer.Start(ctx, "UserStore.CreateUser")
+	defer span.End()
+
+	if err := us.db.WithContext(ctx).Create(user).Error; err != nil {
+		return us.wrapError(ctx, err, "failed to create user")
 	}
 	return nil
 }
 
-func (s *UserStore) GetUserByUID(uid string) (*schema.User, error) {
+func (s *UserStore) GetUserByUID(ctx context.Context, uid string) (*schema.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStore.GetUserByUID")
+	defer span.End()
+
+	var user schema.User
+
+	if err := s.db.WithContext(ctx).Where("uid = ?", uid).First(&user).Error; err != nil {
+		return nil, s.wrapError(ctx, err, "failed to get user")
+	}
+
+	return &user, nil
+}
+
+func (s *UserStore) GetUserById(ctx context.Context, id uint) (*schema.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStore.GetUserById")
+	defer span.End()
+
 	var user schema.User
 
-	if err := s.db.Where("uid = ?", uid).First(&user).Error; err != nil {
-		s.logger.Error("Failed to get user", err)
-		return &user, errors.New("failed to get user")
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, s.wrapError(ctx, err, "failed to get user")
 	}
 
 	return &user, nil
@@ -42,7 +59,7 @@ func (s *UserStore) GetUserByUID(uid string) (*schema.User, error) {
 
 func (s *UserStore) GetUserFromContext(ctx context.Context) (*schema.User, error) {
 	uid := ctx.Value("userID").(string)
-	user, err := s.GetUserByUID(uid)
+	user, err := s.GetUserByUID(ctx, uid)
 	if err != nil {
 		return user, err
 	}
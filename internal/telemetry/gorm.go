@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"errors"
+
+	"github.com/rudransh-shrivastava/rudransh-backend-task/internal/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey is where the plugin keeps the span of a query on the statement
+const spanKey = "telemetry:span"
+
+var tracer = otel.Tracer("github.com/rudransh-shrivastava/rudransh-backend-task/internal/telemetry")
+
+// gormPlugin opens a span for every query run through GORM
+type gormPlugin struct{}
+
+// GORMPlugin returns the plugin tracing the queries of a database, install it with db.Use
+// Only the queries run with a context carrying a span are traced, db.WithContext passes it down
+func GORMPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+func (gormPlugin) Name() string {
+	return "telemetry"
+}
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	return utils.RegisterQueryCallbacks(db, p.Name(), func(operation string) (func(*gorm.DB), func(*gorm.DB)) {
+		return p.before(operation), p.after
+	})
+}
+
+func (gormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return // migrations and background work are not part of a trace
+		}
+		_, span := tracer.Start(ctx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", db.Dialector.Name()), attribute.String("db.operation", operation)))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (gormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	// the statement is recorded with placeholders, the values bound to it can be personal data
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters the traces can be sent to
+const (
+	None   = "none"   // traces are not recorded, incoming trace context is still propagated
+	Stdout = "stdout" // spans are printed as JSON, to inspect them locally without a collector
+	OTLP   = "otlp"   // spans are sent to a collector over OTLP/HTTP, see OTEL_EXPORTER_OTLP_ENDPOINT
+)
+
+// Setup installs the global tracer provider and the W3C trace context propagator
+// The returned function flushes the spans still buffered and stops the exporter
+func Setup(ctx context.Context, exporter, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case None, "":
+		return func(context.Context) error { return nil }, nil
+	case Stdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case OTLP:
+		// the endpoint, headers and TLS are read from the standard OTEL_EXPORTER_OTLP_* variables
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q, must be one of %s, %s or %s", exporter, None, Stdout, OTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the %s traces exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		// a request sampled out upstream is not traced here either
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
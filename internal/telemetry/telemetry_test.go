@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+var (
+	recorder     = tracetest.NewSpanRecorder()
+	installSpans sync.Once
+)
+
+// spans records the spans ended from now on, the global provider can only be installed once
+func spans(t *testing.T) func() []sdktrace.ReadOnlySpan {
+	installSpans.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	before := len(recorder.Ended())
+	return func() []sdktrace.ReadOnlySpan { return recorder.Ended()[before:] }
+}
+
+func TestHandler_ContinuesTraceContext(t *testing.T) {
+	ended := spans(t)
+	r := mux.NewRouter()
+	r.Use(Route)
+	r.HandleFunc("/courses/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	handler := Handler(r, "/healthz")
+
+	req := httptest.NewRequest("GET", "/courses/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+
+	got := ended()
+	if len(got) != 1 {
+		t.Fatalf("expected only the course request to be traced, got %d spans", len(got))
+	}
+	span := got[0]
+	if span.Name() != "GET /courses/{id}" {
+		t.Errorf("expected the span to be named after the route, got %q", span.Name())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the trace of the traceparent header to be continued, got trace %s parent %s", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+}
+
+func TestGORMPlugin(t *testing.T) {
+	type note struct {
+		ID   uint
+		Text string
+	}
+	ended := spans(t)
+	t.Chdir(t.TempDir())
+	db, err := gorm.Open(sqlite.Open("telemetry.sqlite3"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GORMPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&note{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&note{Text: "untraced"})
+	if got := ended(); len(got) != 0 {
+		t.Fatalf("expected the queries outside a trace not to be traced, got %d spans", len(got))
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	db.WithContext(ctx).Create(&note{Text: "traced"})
+	var found []note
+	db.WithContext(ctx).Where("text = ?", "traced").Find(&found)
+	parent.End()
+
+	got := ended()
+	if len(got) != 3 {
+		t.Fatalf("expected 2 query spans and their parent, got %d spans", len(got))
+	}
+	for i, name := range []string{"gorm.create", "gorm.query"} {
+		span := got[i]
+		if span.Name() != name || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected %s under the parent span, got %s", name, span.Name())
+		}
+		attrs := map[string]string{}
+		for _, kv := range span.Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		if attrs["db.sql.table"] != "notes" || attrs["db.system"] != "sqlite" || attrs["db.statement"] == "" {
+			t.Errorf("expected the table, system and statement of %s, got %v", name, attrs)
+		}
+	}
+}
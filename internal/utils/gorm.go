@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// RegisterQueryCallbacks registers callbacks around every create, query, update, delete, row and raw operation of GORM
+// callbacks returns the ones run before and after the given operation, they are named plugin:before_operation and plugin:after_operation
+func RegisterQueryCallbacks(db *gorm.DB, plugin string, callbacks func(operation string) (before, after func(*gorm.DB))) error {
+	var errs []error
+	register := func(operation string, registerBefore, registerAfter func(string, func(*gorm.DB)) error) {
+		before, after := callbacks(operation)
+		errs = append(errs,
+			registerBefore(plugin+":before_"+operation, before),
+			registerAfter(plugin+":after_"+operation, after),
+		)
+	}
+	// the processors of gorm are unexported, so each one is named on its own
+	processors := db.Callback()
+	register("create", processors.Create().Before("gorm:create").Register, processors.Create().After("gorm:create").Register)
+	register("query", processors.Query().Before("gorm:query").Register, processors.Query().After("gorm:query").Register)
+	register("update", processors.Update().Before("gorm:update").Register, processors.Update().After("gorm:update").Register)
+	register("delete", processors.Delete().Before("gorm:delete").Register, processors.Delete().After("gorm:delete").Register)
+	register("row", processors.Row().Before("gorm:row").Register, processors.Row().After("gorm:row").Register)
+	register("raw", processors.Raw().Before("gorm:raw").Register, processors.Raw().After("gorm:raw").Register)
+	return errors.Join(errs...)
+}
@@ -1,21 +1,57 @@
 package logger
 
-import "github.com/sirupsen/logrus"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats of the log lines
+const (
+	Text = "text" // colored, for a terminal
+	JSON = "json" // one object per line, for a log collector
+)
 
 type CustomFormatter struct {
 	logrus.TextFormatter
 }
 
-// Format formats every log entry
+// Format formats every log entry, the fields follow the message as key=value sorted by key
 func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	timestamp := entry.Time.Format("15:04:05") // Hour:Minute:Second format
 
 	level := entry.Level.String()
 	message := entry.Message
 
-	log := timestamp + " " + f.colorizeLevel(level) + " " + message + "\n"
+	log := timestamp + " " + f.colorizeLevel(level) + " " + message
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		log += " " + key + "=" + fieldValue(entry.Data[key])
+	}
+
+	return []byte(log + "\n"), nil
+}
 
-	return []byte(log), nil
+// fieldValue formats the value of a field, quoting it when it has spaces
+func fieldValue(value any) string {
+	var s string
+	if err, ok := value.(error); ok {
+		s = err.Error()
+	} else {
+		s = fmt.Sprint(value)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
 }
 
 func (f *CustomFormatter) colorizeLevel(level string) string {
@@ -37,9 +73,47 @@ func (f *CustomFormatter) colorizeLevel(level string) string {
 	return color + level + "\033[0m"
 }
 
-func NewLogger() *logrus.Logger {
+// contextHook adds the ids of the request to the entries logged with a context, see logrus.Logger.WithContext
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	// set by the request id and auth middlewares of the api
+	if id, ok := entry.Context.Value("requestID").(string); ok {
+		entry.Data["request_id"] = id
+	}
+	if uid, ok := entry.Context.Value("userID").(string); ok {
+		entry.Data["user_id"] = uid
+	}
+	if span := trace.SpanContextFromContext(entry.Context); span.IsValid() {
+		entry.Data["trace_id"] = span.TraceID().String()
+		entry.Data["span_id"] = span.SpanID().String()
+	}
+	return nil
+}
+
+// NewLogger returns a logger writing lines in the given format, text or json, from the given level up
+func NewLogger(format, level string) (*logrus.Logger, error) {
 	log := logrus.New()
-	log.SetFormatter(&CustomFormatter{})
-	log.SetLevel(logrus.DebugLevel)
-	return log
+	switch format {
+	case Text, "":
+		log.SetFormatter(&CustomFormatter{})
+	case JSON:
+		log.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	default:
+		return nil, fmt.Errorf("unknown log format %q, must be %s or %s", format, Text, JSON)
+	}
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	log.SetLevel(lvl)
+	log.AddHook(contextHook{})
+	return log, nil
 }
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger_JSONKeepsFieldsAndRequestIDs(t *testing.T) {
+	log, err := NewLogger(JSON, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	log.SetOutput(&out)
+
+	ctx := context.WithValue(context.WithValue(context.Background(), "requestID", "req-1"), "userID", "uid-1")
+	log.WithContext(ctx).WithField("status", 404).WithError(errors.New("boom")).Error("request failed")
+	log.Debug("below the level")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected the debug line to be dropped, got %d lines", len(lines))
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("expected a json line, got %q: %v", lines[0], err)
+	}
+	for key, want := range map[string]any{"msg": "request failed", "level": "error", "status": 404.0, "error": "boom", "request_id": "req-1", "user_id": "uid-1"} {
+		if line[key] != want {
+			t.Errorf("expected %s to be %v, got %v", key, want, line[key])
+		}
+	}
+}
+
+func TestNewLogger_TextKeepsFields(t *testing.T) {
+	log, err := NewLogger(Text, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.WithField("path", "/api/v1/courses").WithField("user_agent", "curl 8.0").Debug("request served")
+
+	if got := out.String(); !strings.Contains(got, `request served path=/api/v1/courses user_agent="curl 8.0"`) {
+		t.Errorf("expected the sorted fields after the message, got %q", got)
+	}
+}
+
+func TestNewLogger_Invalid(t *testing.T) {
+	if _, err := NewLogger("xml", "info"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+	if _, err := NewLogger(JSON, "loud"); err == nil {
+		t.Error("expected an unknown level to fail")
+	}
+}
@@ -3,8 +3,20 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
+// RequestIDHeader carries the id of a request, it is echoed back in every response
+const RequestIDHeader = "X-Request-ID"
+
+// APIError is the body of every error response
+type APIError struct {
+	Code      string `json:"code"`    // machine readable, derived from the status e.g. "not_found"
+	Message   string `json:"message"` // human readable
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
 func WriteJSONResponse(w http.ResponseWriter, msg any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -12,6 +24,62 @@ func WriteJSONResponse(w http.ResponseWriter, msg any) {
 }
 
 func WriteErrorResponse(w http.ResponseWriter, msg string, status int) {
+	WriteErrorDetails(w, msg, status, nil)
+}
+
+// WriteErrorDetails writes an error response with extra details about the failure
+func WriteErrorDetails(w http.ResponseWriter, msg string, status int, details any) {
+	apiErr := APIError{
+		Code:      ErrorCode(status),
+		Message:   msg,
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	w.Write([]byte(msg))
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+// ErrorCode returns the code of the errors with the given status, e.g. "too_many_requests" for 429
+func ErrorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// ResponseRecorder remembers the status and the size of the response written through it, for the middlewares
+type ResponseRecorder struct {
+	http.ResponseWriter
+	Status int // 0 until the handler writes
+	Bytes  int
+}
+
+func (r *ResponseRecorder) WriteHeader(status int) {
+	if r.Status == 0 {
+		r.Status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += n
+	return n, err
+}
+
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// StatusCode returns the status of the response, 200 if the handler wrote nothing
+func (r *ResponseRecorder) StatusCode() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
 }
@@ -0,0 +1,38 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, see the build target of the Makefile:
+//
+//	go build -ldflags "-X github.com/rudransh-shrivastava/rudransh-backend-task/internal/version.Commit=$(git rev-parse HEAD)"
+var (
+	Commit    string
+	BuildTime string // RFC 3339
+)
+
+// Info describes the build of the running binary
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info, falling back on the version control info stamped by go build
+// for the values not set at build time. Unknown values are empty
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value // time of the commit, the closest there is
+			}
+		}
+	}
+	return info
+}